@@ -0,0 +1,292 @@
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchMaxPoints is the default number of points that will be
+	// buffered by a BatchWriter before it is flushed.
+	DefaultBatchMaxPoints = 5000
+
+	// DefaultBatchFlushInterval is the default interval a BatchWriter will
+	// wait before flushing any buffered points.
+	DefaultBatchFlushInterval = time.Second
+)
+
+// BatchOptions is a set of configuration options for configuring a BatchWriter.
+type BatchOptions struct {
+	// MaxPoints is the maximum number of points to buffer before flushing.
+	// If this is zero, DefaultBatchMaxPoints is used.
+	MaxPoints int
+
+	// MaxBytes is the maximum size of the encoded points to buffer before
+	// flushing. If this is zero, the number of bytes is not limited. A single
+	// point larger than this limit is still written in its own batch.
+	MaxBytes int
+
+	// FlushInterval is the maximum amount of time points are buffered before
+	// they are flushed. If this is zero, DefaultBatchFlushInterval is used.
+	FlushInterval time.Duration
+
+	// ErrorHandler is called with any error that happens while flushing a
+	// batch in the background. Errors from Flush and Close are returned
+	// directly instead.
+	ErrorHandler func(err error)
+}
+
+// BatchWriter buffers points in memory and writes them in batches using a
+// Writer. It is safe to write points from multiple goroutines.
+type BatchWriter struct {
+	w   *Writer
+	opt BatchOptions
+
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	n      int
+	closed bool
+
+	// sending holds a ticket for each call that has cut batches, but has not
+	// handed them to the background goroutine yet. Tickets increase in the
+	// order the batches were cut and sent is signaled when one is removed.
+	sending map[uint64]struct{}
+	ticket  uint64
+	sent    *sync.Cond
+
+	batches chan *batch
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// batch is a set of encoded points waiting to be written.
+type batch struct {
+	data []byte
+
+	// errc receives the result of writing the batch if it is non-nil.
+	errc chan error
+}
+
+// NewBatchWriter creates a new BatchWriter that writes batches of points with
+// the Writer. Close must be called to flush the remaining points and release
+// the background goroutine.
+func NewBatchWriter(w *Writer, opt BatchOptions) *BatchWriter {
+	if opt.MaxPoints <= 0 {
+		opt.MaxPoints = DefaultBatchMaxPoints
+	}
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = DefaultBatchFlushInterval
+	}
+
+	b := &BatchWriter{
+		w:       w,
+		opt:     opt,
+		sending: make(map[uint64]struct{}),
+		batches: make(chan *batch),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	b.sent = sync.NewCond(&b.mu)
+	go b.run()
+	return b
+}
+
+// WritePoint encodes the point and adds it to the current batch. The point
+// will be written when the batch is full, the flush interval is reached, or
+// the BatchWriter is flushed.
+func (b *BatchWriter) WritePoint(pt Point) error {
+	return b.WriteBatch([]Point{pt})
+}
+
+// WriteBatch encodes the points and adds them to the current batch. If any
+// point fails to validate or encode, none of the points are added.
+func (b *BatchWriter) WriteBatch(pts []Point) error {
+	// Encode the points before acquiring the lock and remember where each
+	// point ends so the points can be split between batches.
+	offsets := make([]int, len(pts))
+	data, err := b.w.encodeBatch(pts, offsets)
+	if err != nil {
+		return err
+	}
+
+	var full []*batch
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWriterClosed
+	}
+
+	start := 0
+	for _, end := range offsets {
+		line := data[start:end]
+		start = end
+
+		// If this point would push the batch over the byte limit, cut the
+		// batch before adding this point.
+		if b.opt.MaxBytes > 0 && b.buf.Len() > 0 && b.buf.Len()+len(line) > b.opt.MaxBytes {
+			full = append(full, b.cut())
+		}
+		b.buf.Write(line)
+		b.n++
+
+		if b.n >= b.opt.MaxPoints || (b.opt.MaxBytes > 0 && b.buf.Len() >= b.opt.MaxBytes) {
+			full = append(full, b.cut())
+		}
+	}
+	if len(full) > 0 {
+		defer b.release(b.reserve())
+	}
+	b.mu.Unlock()
+
+	// Send the full batches after releasing the lock so the background
+	// goroutine can continue to flush on the interval.
+	for _, batch := range full {
+		b.batches <- batch
+	}
+	return nil
+}
+
+// Flush writes any buffered points and waits for the write to complete. Full
+// batches that were cut by earlier writes are written before Flush returns.
+func (b *BatchWriter) Flush() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWriterClosed
+	}
+	ticket := b.reserve()
+	batch := b.cut()
+	b.waitSent(ticket)
+	b.mu.Unlock()
+
+	// The background goroutine writes the batches in the order it receives
+	// them so the earlier batches are written before this one.
+	batch.errc = make(chan error, 1)
+	b.batches <- batch
+	b.release(ticket)
+	return <-batch.errc
+}
+
+// Close flushes any buffered points and stops the background goroutine. Any
+// writes after Close is called will return ErrWriterClosed.
+func (b *BatchWriter) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrWriterClosed
+	}
+	b.closed = true
+	batch := b.cut()
+
+	// Wait for any full batches from concurrent writes and flushes to be
+	// handed off before sending the final batch.
+	b.waitSent(b.ticket)
+	b.mu.Unlock()
+
+	batch.errc = make(chan error, 1)
+	b.batches <- batch
+	err := <-batch.errc
+
+	close(b.done)
+	<-b.stopped
+	return err
+}
+
+// cut removes the buffered points and returns them as a new batch. The lock
+// must be held when calling this.
+func (b *BatchWriter) cut() *batch {
+	data := make([]byte, b.buf.Len())
+	copy(data, b.buf.Bytes())
+	b.buf.Reset()
+	b.n = 0
+	return &batch{data: data}
+}
+
+// reserve returns a ticket that is held until the batches cut by the caller
+// have been handed to the background goroutine. The lock must be held when
+// calling this.
+func (b *BatchWriter) reserve() uint64 {
+	ticket := b.ticket
+	b.ticket++
+	b.sending[ticket] = struct{}{}
+	return ticket
+}
+
+// release removes the ticket once its batches have been handed off.
+func (b *BatchWriter) release(ticket uint64) {
+	b.mu.Lock()
+	delete(b.sending, ticket)
+	b.mu.Unlock()
+	b.sent.Broadcast()
+}
+
+// waitSent waits until the batches for every ticket before the given ticket
+// have been handed off. The lock must be held when calling this.
+func (b *BatchWriter) waitSent(ticket uint64) {
+	for {
+		waiting := false
+		for t := range b.sending {
+			if t < ticket {
+				waiting = true
+				break
+			}
+		}
+		if !waiting {
+			return
+		}
+		b.sent.Wait()
+	}
+}
+
+// run is the background goroutine that writes batches to the server.
+func (b *BatchWriter) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.opt.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case batch := <-b.batches:
+			err := b.write(batch.data)
+			if batch.errc != nil {
+				batch.errc <- err
+			} else if err != nil {
+				b.handleError(err)
+			}
+		case <-ticker.C:
+			// Full batches that were cut before the buffered points may
+			// still be waiting to be handed off. Writing the buffer now
+			// would put it ahead of them, so wait for the next tick.
+			b.mu.Lock()
+			if b.buf.Len() == 0 || len(b.sending) > 0 {
+				b.mu.Unlock()
+				continue
+			}
+			batch := b.cut()
+			b.mu.Unlock()
+
+			if err := b.write(batch.data); err != nil {
+				b.handleError(err)
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *BatchWriter) write(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := b.w.WriteContext(context.Background(), data)
+	return err
+}
+
+func (b *BatchWriter) handleError(err error) {
+	if b.opt.ErrorHandler != nil {
+		b.opt.ErrorHandler(err)
+	}
+}
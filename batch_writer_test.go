@@ -0,0 +1,294 @@
+package influxdb_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func newBatchWriter(t *testing.T, url string, opt influxdb.BatchOptions) *influxdb.BatchWriter {
+	return influxdb.NewBatchWriter(newTestWriter(t, url), opt)
+}
+
+func TestBatchWriter_MaxPoints(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	w := newBatchWriter(t, server.URL, influxdb.BatchOptions{
+		MaxPoints:     2,
+		FlushInterval: time.Hour,
+	})
+
+	for i := 0; i < 5; i++ {
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": i},
+		}
+		if err := w.WritePoint(pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.Writes()
+	want := []string{
+		"cpu value=0i\ncpu value=1i\n",
+		"cpu value=2i\ncpu value=3i\n",
+		"cpu value=4i\n",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("batches = %q; want %q", got, want)
+	}
+}
+
+func TestBatchWriter_MaxBytes(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	// Each point is 13 bytes so only two points fit in a batch.
+	w := newBatchWriter(t, server.URL, influxdb.BatchOptions{
+		MaxBytes:      30,
+		FlushInterval: time.Hour,
+	})
+
+	pts := make([]influxdb.Point, 3)
+	for i := range pts {
+		pts[i] = influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": i},
+		}
+	}
+	if err := w.WriteBatch(pts); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.Writes()
+	want := []string{
+		"cpu value=0i\ncpu value=1i\n",
+		"cpu value=2i\n",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("batches = %q; want %q", got, want)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePoint(pts[0]); err != influxdb.ErrWriterClosed {
+		t.Fatalf("got error %v; want %v", err, influxdb.ErrWriterClosed)
+	}
+}
+
+func TestBatchWriter_FlushInterval(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	w := newBatchWriter(t, server.URL, influxdb.BatchOptions{
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer w.Close()
+
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": 5.0},
+	}
+	if err := w.WritePoint(pt); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(server.Writes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the batch to be flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if got, want := server.Writes()[0], "cpu value=5\n"; got != want {
+		t.Fatalf("batch = %q; want %q", got, want)
+	}
+}
+
+func TestBatchWriter_Concurrent(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	w := newBatchWriter(t, server.URL, influxdb.BatchOptions{
+		MaxPoints:     7,
+		FlushInterval: time.Millisecond,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pt := influxdb.Point{
+					Name:   "cpu",
+					Fields: map[string]interface{}{"value": j},
+				}
+				if err := w.WritePoint(pt); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for _, batch := range server.Writes() {
+		n += strings.Count(batch, "\n")
+	}
+	if n != 1000 {
+		t.Fatalf("wrote %d points; want %d", n, 1000)
+	}
+}
+
+func TestBatchWriter_ErrorHandler(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	server.SetDown(true)
+
+	errs := make(chan error, 1)
+	w := newBatchWriter(t, server.URL, influxdb.BatchOptions{
+		MaxPoints:     1,
+		FlushInterval: time.Hour,
+		ErrorHandler: func(err error) {
+			errs <- err
+		},
+	})
+	defer w.Close()
+
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": 5.0},
+	}
+	if err := w.WritePoint(pt); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		if got, want := err.Error(), "expected error"; !strings.HasPrefix(got, want) {
+			t.Fatalf("got error %q; want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the error handler")
+	}
+}
+
+func TestBatchWriter_FlushDuringClose(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		// The server holds the first batch so the Flush and Close below both
+		// have to wait for the background goroutine.
+		release := make(chan struct{})
+		server := newTestServer(t)
+		handler := server.Config.Handler
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			handler.ServeHTTP(w, r)
+		})
+
+		w := newBatchWriter(t, server.URL, influxdb.BatchOptions{
+			MaxPoints:     1,
+			FlushInterval: time.Hour,
+		})
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": 1},
+		}
+		if err := w.WritePoint(pt); err != nil {
+			t.Fatal(err)
+		}
+
+		flushed := make(chan error, 1)
+		go func() { flushed <- w.Flush() }()
+		time.Sleep(time.Millisecond)
+		closed := make(chan error, 1)
+		go func() { closed <- w.Close() }()
+		time.Sleep(time.Millisecond)
+		close(release)
+
+		for _, ch := range []chan error{flushed, closed} {
+			select {
+			case err := <-ch:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for Flush and Close")
+			}
+		}
+		server.Close()
+	}
+}
+
+func TestBatchWriter_IntervalFlushOrder(t *testing.T) {
+	points := func(values ...int) []influxdb.Point {
+		pts := make([]influxdb.Point, len(values))
+		for i, v := range values {
+			pts[i] = influxdb.Point{
+				Name:   "cpu",
+				Fields: map[string]interface{}{"value": v},
+			}
+		}
+		return pts
+	}
+
+	for i := 0; i < 20; i++ {
+		// The server holds the first batch so the second full batch is still
+		// waiting to be handed off when the flush interval is reached.
+		release := make(chan struct{})
+		server := newTestServer(t)
+		handler := server.Config.Handler
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			handler.ServeHTTP(w, r)
+		})
+
+		w := newBatchWriter(t, server.URL, influxdb.BatchOptions{
+			MaxPoints:     2,
+			FlushInterval: time.Millisecond,
+		})
+		if err := w.WriteBatch(points(1, 2)); err != nil {
+			t.Fatal(err)
+		}
+		written := make(chan error, 1)
+		go func() { written <- w.WriteBatch(points(3, 4)) }()
+		time.Sleep(5 * time.Millisecond)
+		if err := w.WriteBatch(points(5)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+		close(release)
+
+		if err := <-written; err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"cpu value=1i", "cpu value=2i", "cpu value=3i", "cpu value=4i", "cpu value=5i"}
+		if got := server.Lines(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("lines = %q; want %q", got, want)
+		}
+		server.Close()
+	}
+}
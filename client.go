@@ -2,6 +2,7 @@ package influxdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,6 +23,22 @@ import (
 type Auth struct {
 	Username string
 	Password string
+
+	// Token is an API token for an InfluxDB 2.x server. If this is set, it
+	// is sent with the Token authorization scheme instead of the username
+	// and password.
+	Token string
+}
+
+// setAuth adds the credentials to the request.
+func (a *Auth) setAuth(req *http.Request) {
+	if a == nil {
+		return
+	} else if a.Token != "" {
+		req.Header.Set("Authorization", "Token "+a.Token)
+		return
+	}
+	req.SetBasicAuth(a.Username, a.Password)
 }
 
 // Client is a client that communicates with an InfluxDB server.
@@ -42,6 +59,15 @@ type Client struct {
 
 	// Auth holds the authentication credentials.
 	Auth *Auth
+
+	// RetryPolicy configures how failed requests are retried. If this is
+	// nil, failed requests are not retried.
+	RetryPolicy *RetryPolicy
+
+	// Nodes sends the requests to multiple servers. If this is set, Proto
+	// and Addr are ignored and the Path of each request is replaced with the
+	// path of the node it is sent to.
+	Nodes *NodePool
 }
 
 // NewClient creates a new client pointed to the parsed hostname.
@@ -74,16 +100,26 @@ type ServerInfo struct {
 // Ping sends a ping to the server to verify the server is alive and accepting
 // HTTP requests.
 func (c *Client) Ping() (ServerInfo, error) {
+	return c.PingContext(context.Background())
+}
+
+// PingContext sends a ping to the server using the context to cancel the
+// request.
+func (c *Client) PingContext(ctx context.Context) (ServerInfo, error) {
 	u := c.url("/ping")
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return ServerInfo{}, ErrPing{Cause: err}
 	}
+	req = req.WithContext(ctx)
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.send(req, true)
 	if err != nil {
 		return ServerInfo{}, ErrPing{Cause: err}
-	} else if resp.StatusCode/100 != 2 {
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
 		return ServerInfo{}, ErrPing{Cause: errors.New("incorrect status code")}
 	}
 	return ServerInfo{
@@ -101,7 +137,13 @@ func (c *Client) Querier() *Querier {
 // This request will use a GET and can only contain statements that read from
 // the database.
 func (c *Client) NewReadonlyQueryRequest(q interface{}, opt QueryOptions) (*http.Request, error) {
-	return c.newQueryRequest(q, true, opt)
+	return c.newQueryRequest(context.Background(), q, true, opt)
+}
+
+// NewReadonlyQueryRequestContext creates a new GET HTTP request for the query
+// that will be cancelled when the context is done.
+func (c *Client) NewReadonlyQueryRequestContext(ctx context.Context, q interface{}, opt QueryOptions) (*http.Request, error) {
+	return c.newQueryRequest(ctx, q, true, opt)
 }
 
 // NewQueryRequest creates a new POST HTTP request for the query.
@@ -109,10 +151,17 @@ func (c *Client) NewReadonlyQueryRequest(q interface{}, opt QueryOptions) (*http
 // This request will use a POST and can contain both statements that read and
 // modify the database.
 func (c *Client) NewQueryRequest(q interface{}, opt QueryOptions) (*http.Request, error) {
-	return c.newQueryRequest(q, false, opt)
+	return c.newQueryRequest(context.Background(), q, false, opt)
 }
 
-// newQueryRequest creates a new HTTP request for the query.
+// NewQueryRequestContext creates a new POST HTTP request for the query that
+// will be cancelled when the context is done.
+func (c *Client) NewQueryRequestContext(ctx context.Context, q interface{}, opt QueryOptions) (*http.Request, error) {
+	return c.newQueryRequest(ctx, q, false, opt)
+}
+
+// newQueryRequest creates a new HTTP request for the query. The request is
+// bound to the context.
 //
 // The first parameter is for a query. This can be either a string or an
 // io.Reader. If the query is an io.Reader, the query is sent as a file using
@@ -125,7 +174,7 @@ func (c *Client) NewQueryRequest(q interface{}, opt QueryOptions) (*http.Request
 // the query is encoded in the url parameters so it can be logged on the
 // server. If we use an io.Reader, the entire file is read and encoded in the
 // body.
-func (c *Client) newQueryRequest(q interface{}, readonly bool, opt QueryOptions) (*http.Request, error) {
+func (c *Client) newQueryRequest(ctx context.Context, q interface{}, readonly bool, opt QueryOptions) (*http.Request, error) {
 	values := url.Values{}
 
 	var body io.Reader
@@ -207,22 +256,27 @@ func (c *Client) newQueryRequest(q interface{}, readonly bool, opt QueryOptions)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
-	if c.Auth != nil {
-		req.SetBasicAuth(c.Auth.Username, c.Auth.Password)
-	}
+	c.Auth.setAuth(req)
 
 	switch opt.Format {
 	case "text/csv", "csv":
 		req.Header.Set("Accept", "text/csv")
 	case "application/json", "json", "":
 		req.Header.Set("Accept", "application/json")
+	case "application/x-msgpack", "msgpack":
+		req.Header.Set("Accept", "application/x-msgpack")
 	default:
 		return nil, fmt.Errorf("unknown format: %s", opt.Format)
 	}
+
+	if err := opt.Compression.setAcceptEncoding(req); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
@@ -233,6 +287,13 @@ func (c *Client) Select(q interface{}, opts ...QueryOption) (Cursor, error) {
 	return querier.Select(q, opts...)
 }
 
+// SelectContext executes a query and parses the results from the stream. The
+// context will cancel the request and any reads from the returned Cursor.
+func (c *Client) SelectContext(ctx context.Context, q interface{}, opts ...QueryOption) (Cursor, error) {
+	querier := Querier{c: c}
+	return querier.SelectContext(ctx, q, opts...)
+}
+
 // Execute executes a query and returns if any error occurred.
 // To specify options, use Querier to create a Querier and set the options on that.
 func (c *Client) Execute(q interface{}, opts ...QueryOption) error {
@@ -240,16 +301,33 @@ func (c *Client) Execute(q interface{}, opts ...QueryOption) error {
 	return querier.Execute(q, opts...)
 }
 
+// ExecuteContext executes a query and returns if any error occurred. The
+// context will cancel the request.
+func (c *Client) ExecuteContext(ctx context.Context, q interface{}, opts ...QueryOption) error {
+	querier := Querier{c: c}
+	return querier.ExecuteContext(ctx, q, opts...)
+}
+
 func (c *Client) Writer() *Writer {
 	return &Writer{c: c}
 }
 
-// url constructs a URL object for this client.
+// send sends a single attempt of the request. If the client has a NodePool,
+// the pool chooses the server that receives the request.
+func (c *Client) send(req *http.Request, idempotent bool) (*http.Response, error) {
+	if c.Nodes != nil {
+		return c.Nodes.do(c, req, idempotent)
+	}
+	return c.Client.Do(req)
+}
+
+// url constructs a URL object for this client. The path is joined onto the
+// Path of the client so the server can be reached behind a path prefix.
 func (c *Client) url(path string) url.URL {
 	u := url.URL{
 		Scheme: c.Proto,
 		Host:   c.Addr,
-		Path:   path,
+		Path:   joinPath(c.Path, path),
 	}
 
 	if u.Scheme == "" {
@@ -260,3 +338,12 @@ func (c *Client) url(path string) url.URL {
 	}
 	return u
 }
+
+// joinPath joins the path onto the prefix with exactly one slash between them.
+func joinPath(prefix, path string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return path
+	}
+	return prefix + "/" + strings.TrimLeft(path, "/")
+}
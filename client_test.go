@@ -1,6 +1,7 @@
 package influxdb_test
 
 import (
+	"context"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	influxdb "github.com/influxdata/influxdb-client"
 )
@@ -61,7 +63,7 @@ func TestClient_Do(t *testing.T) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		t.Errorf("StatusCode = %q; want %q", resp.StatusCode, http.StatusNoContent)
+		t.Errorf("StatusCode = %d; want %d", resp.StatusCode, http.StatusNoContent)
 	}
 
 	select {
@@ -122,6 +124,29 @@ func TestClient_Ping_Failure(t *testing.T) {
 	}
 }
 
+func TestClient_PingContext_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.PingContext(ctx); err == nil {
+		t.Fatal("expected error, got nil")
+	} else if e, ok := err.(influxdb.ErrPing); !ok {
+		t.Fatalf("got error type %T; want %T", err, e)
+	} else if ctx.Err() == nil {
+		t.Fatalf("unexpected error before the deadline: %v", err)
+	}
+}
+
 func TestClient_NewQueryRequest_String(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -437,7 +462,7 @@ func TestClient_Select(t *testing.T) {
 	})
 
 	exp := [][]interface{}{
-		[]interface{}{"1970-01-01T00:00:00Z", float64(5)},
+		[]interface{}{"1970-01-01T00:00:00Z", int64(5)},
 	}
 	if !reflect.DeepEqual(got, exp) {
 		t.Fatalf("Values = %q; want %q", got, exp)
@@ -513,3 +538,49 @@ func TestClient_Execute_Failure(t *testing.T) {
 		t.Errorf("error message %q; want %q", e.Err, "expected err")
 	}
 }
+
+func TestClient_PathPrefix(t *testing.T) {
+	for _, prefix := range []string{"/influx", "/influx/", "/my influx/"} {
+		t.Run(prefix, func(t *testing.T) {
+			base := strings.TrimRight(prefix, "/")
+
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				switch r.URL.Path {
+				case base + "/ping":
+					w.WriteHeader(http.StatusNoContent)
+				case base + "/query":
+					w.Header().Add("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					io.WriteString(w, `{"results":[{}]}`)
+				case base + "/write":
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			client, err := influxdb.NewClient(server.URL + (&url.URL{Path: prefix}).EscapedPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Ping(); err != nil {
+				t.Errorf("ping: %v", err)
+			}
+			if err := client.Execute("CREATE DATABASE db0"); err != nil {
+				t.Errorf("execute: %v", err)
+			}
+			if _, err := client.Writer().Write([]byte("cpu value=5\n")); err != nil {
+				t.Errorf("write: %v", err)
+			}
+
+			want := []string{base + "/ping", base + "/query", base + "/write"}
+			if !reflect.DeepEqual(paths, want) {
+				t.Errorf("paths = %q; want %q", paths, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package influxdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Compression is the compression used for the body of a request or response.
+type Compression string
+
+const (
+	// CompressionNone sends and receives bodies without compression.
+	CompressionNone = Compression("")
+
+	// CompressionGzip compresses bodies with gzip.
+	CompressionGzip = Compression("gzip")
+)
+
+func (c Compression) String() string {
+	return string(c)
+}
+
+// setAcceptEncoding asks the server to compress the response to the request.
+func (c Compression) setAcceptEncoding(req *http.Request) error {
+	switch c {
+	case CompressionNone:
+	case CompressionGzip:
+		// Setting the header ourselves stops the transport from
+		// decompressing the response so it is decompressed by the cursor.
+		req.Header.Set("Accept-Encoding", "gzip")
+	default:
+		return fmt.Errorf("unknown compression: %s", c)
+	}
+	return nil
+}
+
+// NoCompressionLevel is a compression level that selects gzip.NoCompression.
+// The value of gzip.NoCompression is zero, which selects the default level
+// when used as a compression level, so this is used in its place.
+const NoCompressionLevel = gzip.HuffmanOnly - 1
+
+// gzipWriterPools holds a pool of gzip writers for each compression level
+// from gzip.HuffmanOnly to gzip.BestCompression.
+var gzipWriterPools [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+// gzipCompress compresses the data with the compression level and appends it
+// to the buffer. A level of zero uses gzip.DefaultCompression and
+// NoCompressionLevel uses gzip.NoCompression.
+func gzipCompress(buf *bytes.Buffer, data []byte, level int) error {
+	switch {
+	case level == 0:
+		level = gzip.DefaultCompression
+	case level == NoCompressionLevel:
+		level = gzip.NoCompression
+	case level < gzip.HuffmanOnly || level > gzip.BestCompression:
+		return fmt.Errorf("invalid gzip compression level: %d", level)
+	}
+
+	pool := &gzipWriterPools[level-gzip.HuffmanOnly]
+	gz, _ := pool.Get().(*gzip.Writer)
+	if gz == nil {
+		// The level has already been checked so this cannot fail.
+		gz, _ = gzip.NewWriterLevel(buf, level)
+	} else {
+		gz.Reset(buf)
+	}
+	defer func() {
+		// Stop the pooled writer from holding onto the buffer.
+		gz.Reset(ioutil.Discard)
+		pool.Put(gz)
+	}()
+
+	if _, err := gz.Write(data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// gzipReadCloser decompresses a response body as it is read and closes the
+// response body when it is closed.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.body.Close()
+}
+
+// responseBody returns the body of the response. If the body was compressed,
+// it is decompressed as it is read. The caller must close the returned body.
+func responseBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp.Body, nil
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: gz, body: resp.Body}, nil
+}
@@ -0,0 +1,136 @@
+package influxdb_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func TestWriter_Gzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Content-Encoding"), "gzip"; got != want {
+			t.Errorf("Content-Encoding = %q; want %q", got, want)
+		}
+
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(gz)
+		if got, want := string(data), "cpu value=1\ncpu value=2\n"; got != want {
+			t.Errorf("body = %q; want %q", got, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.Compression = influxdb.CompressionGzip
+	for _, level := range []int{0, gzip.BestSpeed, gzip.BestCompression, gzip.HuffmanOnly, influxdb.NoCompressionLevel} {
+		writer.CompressionLevel = level
+		if n, err := writer.Write([]byte("cpu value=1\ncpu value=2\n")); err != nil {
+			t.Fatalf("level %d: unexpected error: %s", level, err)
+		} else if n != 24 {
+			t.Fatalf("level %d: got %d bytes written; want 24", level, n)
+		}
+	}
+
+	writer.CompressionLevel = 10
+	if _, err := writer.Write([]byte("cpu value=1\n")); err == nil {
+		t.Fatal("expected error for an invalid compression level")
+	}
+}
+
+func TestQuerier_Select_Gzip(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Accept-Encoding"), "gzip"; got != want {
+			t.Errorf("Accept-Encoding = %q; want %q", got, want)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value"],"values":[[0,1]]}]}]}` + "\n"))
+		gz.Flush()
+		w.(http.Flusher).Flush()
+
+		// Wait until the client has read the first result so the response
+		// is known to be decompressed as it is streamed.
+		<-release
+		gz.Write([]byte(`{"results":[{"statement_id":1,"series":[{"name":"mem","columns":["time","free"],"values":[[0,2]]}]}]}` + "\n"))
+		gz.Close()
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	querier := client.Querier()
+	querier.Compression = influxdb.CompressionGzip
+	cur, err := querier.Select("SELECT value FROM cpu; SELECT free FROM mem")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer cur.Close()
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if got, want := series.Name(), "cpu"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	release <- struct{}{}
+
+	result, err = cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	series, err = result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if got, want := series.Name(), "mem"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestQuerier_Select_GzipError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusBadRequest)
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"error":"error parsing query"}`))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	querier := client.Querier()
+	querier.Compression = influxdb.CompressionGzip
+	if _, err := querier.Select("SELECT"); err == nil || err.Error() != "error parsing query" {
+		t.Fatalf("got error %v; want %q", err, "error parsing query")
+	}
+}
@@ -0,0 +1,85 @@
+package influxdb
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// contextReader wraps an io.ReadCloser so that reads are aborted when the
+// context is cancelled. When the context is done, the underlying reader is
+// closed so any blocked read returns and all future reads return the error
+// from the context.
+type contextReader struct {
+	ctx  context.Context
+	r    io.ReadCloser
+	once sync.Once
+	err  error
+
+	// done is closed to stop the watcher once the reader is closed or has
+	// returned an error.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// newContextReader returns a reader that is closed when the context is done.
+// If the context can never be cancelled, the original reader is returned.
+func newContextReader(ctx context.Context, r io.ReadCloser) io.ReadCloser {
+	if ctx == nil || ctx.Done() == nil {
+		return r
+	}
+
+	cr := &contextReader{
+		ctx:  ctx,
+		r:    r,
+		done: make(chan struct{}),
+	}
+	go cr.watch()
+	return cr
+}
+
+// watch waits for the context to be done and closes the underlying reader.
+func (r *contextReader) watch() {
+	select {
+	case <-r.ctx.Done():
+		r.close()
+	case <-r.done:
+	}
+}
+
+func (r *contextReader) Read(p []byte) (n int, err error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err = r.r.Read(p)
+	if err != nil {
+		// Nothing more will be read so the watcher is no longer needed.
+		r.stop()
+
+		// If the read failed because the context was cancelled, report the
+		// context error instead of whatever error the closed reader returned.
+		if ctxErr := r.ctx.Err(); ctxErr != nil {
+			return n, ctxErr
+		}
+	}
+	return n, err
+}
+
+func (r *contextReader) Close() error {
+	r.close()
+	return r.err
+}
+
+// close closes the underlying reader exactly once and stops the watcher.
+func (r *contextReader) close() {
+	r.once.Do(func() {
+		r.stop()
+		r.err = r.r.Close()
+	})
+}
+
+// stop stops the watcher without closing the underlying reader.
+func (r *contextReader) stop() {
+	r.doneOnce.Do(func() { close(r.done) })
+}
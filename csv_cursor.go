@@ -0,0 +1,354 @@
+package influxdb
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvCursor reads the CSV output from the server.
+//
+// The CSV output is a flattened version of the JSON output. Each statement
+// starts with a header row containing the name and tags columns followed by
+// the column names. Every other row contains the measurement name, the tags
+// formatted as a comma-separated list of key/value pairs, and the values.
+// Consecutive rows with the same name and tags belong to the same series.
+//
+// The server also prints a new header when the columns change within a
+// single statement. Since the CSV output has no statement identifiers, this
+// cursor treats every header as the start of a new ResultSet.
+type csvCursor struct {
+	r  io.ReadCloser
+	cr *csv.Reader
+
+	// next holds a record that has been read, but not consumed yet.
+	next []string
+	err  error
+
+	cur *csvResult
+}
+
+func newCSVCursor(r io.ReadCloser) *csvCursor {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	return &csvCursor{
+		r:  r,
+		cr: cr,
+	}
+}
+
+// read returns the next record from the stream.
+func (c *csvCursor) read() ([]string, error) {
+	if c.next != nil {
+		rec := c.next
+		c.next = nil
+		return rec, nil
+	} else if c.err != nil {
+		return nil, c.err
+	}
+
+	rec, err := c.cr.Read()
+	if err != nil {
+		c.err = err
+		return nil, err
+	}
+	return rec, nil
+}
+
+// unread pushes the record back so it is returned by the next call to read.
+func (c *csvCursor) unread(rec []string) {
+	c.next = rec
+}
+
+func (c *csvCursor) NextSet() (ResultSet, error) {
+	if c.cur != nil {
+		// Invalidate the current result so it stops reading from the cursor.
+		c.cur.cur = nil
+		c.cur = nil
+	}
+
+	// Skip to the next header.
+	for {
+		rec, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+
+		if isCSVErrorHeader(rec) {
+			msg, err := c.read()
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			return nil, ErrResult{Err: strings.Join(msg, ",")}
+		} else if !isCSVHeader(rec) {
+			continue
+		}
+
+		c.cur = &csvResult{
+			columns: rec[2:],
+			cur:     c,
+		}
+		return c.cur, nil
+	}
+}
+
+func (c *csvCursor) Close() error {
+	if err := c.r.Close(); err != nil {
+		return err
+	}
+	c.next = nil
+	return nil
+}
+
+// isCSVHeader returns true if the record is a header row. A data row can never
+// be confused with a header because the tags column of a data row is either
+// empty or contains at least one key/value pair.
+func isCSVHeader(rec []string) bool {
+	return len(rec) >= 2 && rec[0] == "name" && rec[1] == "tags"
+}
+
+// isCSVErrorHeader returns true if the record is the header for an error.
+func isCSVErrorHeader(rec []string) bool {
+	return len(rec) == 1 && rec[0] == "error"
+}
+
+type csvResult struct {
+	columns       []string
+	columnsByName map[string]int
+	cur           *csvCursor
+	series        *csvSeries
+}
+
+func (r *csvResult) Columns() []string {
+	return r.columns
+}
+
+func (r *csvResult) Index(name string) int {
+	if r.columnsByName == nil {
+		r.columnsByName = make(map[string]int, len(r.columns))
+		for i, s := range r.columns {
+			if _, ok := r.columnsByName[s]; !ok {
+				r.columnsByName[s] = i
+			}
+		}
+	}
+
+	if i, ok := r.columnsByName[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// Messages always returns nil because the server does not include
+// informational messages in the CSV output.
+func (r *csvResult) Messages() []*Message {
+	return nil
+}
+
+func (r *csvResult) NextSeries() (Series, error) {
+	if r.cur == nil {
+		return nil, io.EOF
+	}
+
+	// Discard the remaining rows in the current series.
+	if r.series != nil {
+		for {
+			if _, err := r.series.NextRow(); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
+			}
+		}
+		r.series.invalid = true
+		r.series = nil
+	}
+
+	rec, err := r.cur.read()
+	if err != nil {
+		return nil, err
+	} else if isCSVHeader(rec) || isCSVErrorHeader(rec) {
+		// This is the start of the next ResultSet.
+		r.cur.unread(rec)
+		return nil, io.EOF
+	}
+	r.cur.unread(rec)
+
+	r.series = &csvSeries{
+		name:    rec[0],
+		rawTags: rec[1],
+		tags:    parseCSVTags(rec[1]),
+		r:       r,
+	}
+	return r.series, nil
+}
+
+type csvSeries struct {
+	name    string
+	rawTags string
+	tags    Tags
+	sz      int
+
+	r        *csvResult
+	complete bool
+	invalid  bool
+}
+
+func (s *csvSeries) Name() string {
+	return s.name
+}
+
+func (s *csvSeries) Tags() Tags {
+	return s.tags
+}
+
+func (s *csvSeries) Columns() []string {
+	return s.r.Columns()
+}
+
+// Len returns the number of rows read so far. The CSV output does not contain
+// the length of a series so the series is only known to be complete after the
+// last row has been read.
+func (s *csvSeries) Len() (n int, complete bool) {
+	return s.sz, s.complete
+}
+
+func (s *csvSeries) NextRow() (Row, error) {
+	if s.complete || s.invalid || s.r.cur == nil {
+		return nil, io.EOF
+	}
+
+	rec, err := s.r.cur.read()
+	if err != nil {
+		if err == io.EOF {
+			s.complete = true
+		}
+		return nil, err
+	} else if len(rec) < 2 || rec[0] != s.name || rec[1] != s.rawTags || isCSVHeader(rec) {
+		// This row belongs to the next series or the next result.
+		s.r.cur.unread(rec)
+		s.complete = true
+		return nil, io.EOF
+	}
+	s.sz++
+
+	values := make([]interface{}, len(rec)-2)
+	for i, v := range rec[2:] {
+		values[i] = parseCSVValue(v)
+	}
+	return csvRow{values: values, result: s.r}, nil
+}
+
+type csvRow struct {
+	values []interface{}
+	result *csvResult
+}
+
+func (r csvRow) Time() time.Time {
+	switch v := r.ValueByName("time").(type) {
+	case int64:
+		return time.Unix(0, v).UTC()
+	case string:
+		t, _ := time.Parse(time.RFC3339Nano, v)
+		return t
+	}
+	return time.Time{}
+}
+
+func (r csvRow) Value(index int) interface{} {
+	return r.values[index]
+}
+
+func (r csvRow) Values() []interface{} {
+	return r.values
+}
+
+func (r csvRow) ValueByName(column string) interface{} {
+	index := r.result.Index(column)
+	if index == -1 || index >= len(r.values) {
+		return nil
+	}
+	return r.values[index]
+}
+
+// parseCSVValue converts a value from the CSV output into the same type the
+// JSON cursor would return. The CSV output does not keep type information so
+// the type is inferred from the text.
+func parseCSVValue(s string) interface{} {
+	switch s {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if c := s[0]; c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9') {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v
+		} else if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+// parseCSVTags parses the tags column of the CSV output. The tags are
+// formatted the same way as the line protocol with the special characters
+// escaped by a backslash.
+func parseCSVTags(s string) Tags {
+	if s == "" {
+		return nil
+	}
+
+	var tags Tags
+	for len(s) > 0 {
+		var pair string
+		pair, s = splitEscaped(s, ',')
+
+		key, value := splitEscaped(pair, '=')
+		tags = append(tags, Tag{
+			Key:   unescapeTag(key),
+			Value: unescapeTag(value),
+		})
+	}
+	return tags
+}
+
+// splitEscaped splits the string at the first unescaped separator.
+func splitEscaped(s string, sep byte) (string, string) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// unescapeTag removes the escape characters from a tag key or value.
+func unescapeTag(in string) string {
+	if strings.IndexByte(in, '\\') == -1 {
+		return in
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(in); i++ {
+		if in[i] == '\\' && i+1 < len(in) {
+			switch in[i+1] {
+			case ',', ' ', '=':
+				i++
+			}
+		}
+		buf.WriteByte(in[i])
+	}
+	return buf.String()
+}
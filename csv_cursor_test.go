@@ -0,0 +1,189 @@
+package influxdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+// readAll reads every value from the cursor into a nested structure that can be compared.
+func readAll(t *testing.T, cur influxdb.Cursor) []interface{} {
+	var results []interface{}
+	if err := influxdb.EachResult(cur, func(result influxdb.ResultSet) error {
+		var series []interface{}
+		if err := influxdb.EachSeries(result, func(s influxdb.Series) error {
+			var rows [][]interface{}
+			if err := influxdb.EachRow(s, func(row influxdb.Row) error {
+				rows = append(rows, row.Values())
+				return nil
+			}); err != nil {
+				return err
+			}
+			series = append(series, []interface{}{s.Name(), s.Tags(), s.Columns(), rows})
+			return nil
+		}); err != nil {
+			return err
+		}
+		results = append(results, []interface{}{result.Columns(), series})
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return results
+}
+
+func TestCursor_CSV_Basic(t *testing.T) {
+	r := strings.NewReader("name,tags,time,value\ncpu,,1262304000000000000,2\ncpu,,1262304010000000000,3\n")
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(r), "text/csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if got, want := result.Columns(), []string{"time", "value"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if got, want := series.Name(), "cpu"; got != want {
+		t.Fatalf("got %#v; want %#v", got, want)
+	} else if got, want := series.Tags(), influxdb.Tags(nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	} else if want := []interface{}{int64(1262304000000000000), int64(2)}; !reflect.DeepEqual(row.Values(), want) {
+		t.Fatalf("got %#v; want %#v", row.Values(), want)
+	}
+
+	if got, want := row.Time(), mustParseTime("2010-01-01T00:00:00Z"); got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+	if got, want := row.ValueByName("value"), int64(2); got != want {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+
+	if got, err := series.NextRow(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	} else if want := []interface{}{int64(1262304010000000000), int64(3)}; !reflect.DeepEqual(got.Values(), want) {
+		t.Fatalf("got %#v; want %#v", got.Values(), want)
+	}
+
+	if _, err := series.NextRow(); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	if sz, complete := series.Len(); sz != 2 || !complete {
+		t.Fatalf("got (%d, %v); want (2, true)", sz, complete)
+	}
+	if _, err := result.NextSeries(); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	if _, err := cur.NextSet(); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestCursor_CSV_ResultError(t *testing.T) {
+	r := strings.NewReader("error\nexpected err\n")
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(r), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cur.NextSet()
+	if want := (influxdb.ErrResult{Err: "expected err"}); err != want {
+		t.Fatalf("got error %#v; want %#v", err, want)
+	}
+}
+
+func TestCursor_CSV_SkipSeries(t *testing.T) {
+	r := strings.NewReader(`name,tags,time,value
+cpu,host=server01,0,1
+cpu,host=server01,10,2
+cpu,host=server02,0,3
+
+name,tags,time,value
+mem,,0,4
+`)
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(r), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	// Read the first series without reading any rows.
+	if _, err := result.NextSeries(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, want := series.Tags(), (influxdb.Tags{{Key: "host", Value: "server02"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+
+	// Skip the remaining rows in this result.
+	result, err = cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	series, err = result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	} else if got, want := series.Name(), "mem"; got != want {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
+
+// TestCursor_CSV_JSONParity ensures the CSV cursor returns the same values as
+// the JSON cursor for the same output from the server.
+func TestCursor_CSV_JSONParity(t *testing.T) {
+	jsonOut := `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"server 01","region":"us,west"},"columns":["time","value","status","ok"],"values":[[1262304000000000001,2.5,"running",true],[1262304010000000000,3,null,false]],"partial":true}],"partial":true}]}
+{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"server 01","region":"us,west"},"columns":["time","value","status","ok"],"values":[[1262304020000000000,4,"stopped",true]]},{"name":"cpu","tags":{"host":"server02","region":"us\\=east"},"columns":["time","value","status","ok"],"values":[[1262304000000000000,-1.25,"idle",false]]}]}]}
+{"results":[{"statement_id":1,"series":[{"name":"mem","columns":["time","free"],"values":[[1262304000000000000,18446744073709551615]]}]}]}
+`
+	csvOut := `name,tags,time,value,status,ok
+cpu,"host=server\ 01,region=us\,west",1262304000000000001,2.5,running,true
+cpu,"host=server\ 01,region=us\,west",1262304010000000000,3,,false
+cpu,"host=server\ 01,region=us\,west",1262304020000000000,4,stopped,true
+cpu,"host=server02,region=us\\=east",1262304000000000000,-1.25,idle,false
+
+name,tags,time,free
+mem,,1262304000000000000,18446744073709551615
+`
+	jsonCur, err := influxdb.NewCursor(ioutil.NopCloser(strings.NewReader(jsonOut)), "application/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvCur, err := influxdb.NewCursor(ioutil.NopCloser(strings.NewReader(csvOut)), "text/csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := readAll(t, jsonCur)
+	got := readAll(t, csvCur)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("csv output does not match json output:\n\ngot=%#v\nwant=%#v\n", got, want)
+	}
+}
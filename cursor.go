@@ -1,6 +1,7 @@
 package influxdb
 
 import (
+	"context"
 	"io"
 	"time"
 )
@@ -74,10 +75,26 @@ type Row interface {
 // NewCursor constructs a new cursor from the io.ReadCloser and parses it with
 // the appropriate decoder for the format. The following formatters are supported:
 // json (application/json)
+// csv (text/csv)
+// msgpack (application/x-msgpack)
+// flux (the annotated CSV output of a Flux query)
 func NewCursor(r io.ReadCloser, format string) (Cursor, error) {
+	return NewCursorContext(context.Background(), r, format)
+}
+
+// NewCursorContext constructs a new cursor from the io.ReadCloser like
+// NewCursor. When the context is done, the io.ReadCloser is closed and any
+// in-progress or future reads from the cursor return the context error.
+func NewCursorContext(ctx context.Context, r io.ReadCloser, format string) (Cursor, error) {
 	switch format {
 	case "json", "application/json":
-		return newJSONCursor(r), nil
+		return newJSONCursor(newContextReader(ctx, r)), nil
+	case "csv", "text/csv":
+		return newCSVCursor(newContextReader(ctx, r)), nil
+	case "msgpack", "application/x-msgpack":
+		return newMsgpackCursor(newContextReader(ctx, r)), nil
+	case "flux":
+		return newFluxCursor(newContextReader(ctx, r)), nil
 	default:
 		return nil, ErrUnknownFormat{Format: format}
 	}
@@ -0,0 +1,253 @@
+package influxdb
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Encoder encodes points in the line protocol into a reusable buffer. Once
+// its buffers have grown, an Encoder encodes points without allocating for
+// most field types. An Encoder is not safe for concurrent use.
+type Encoder struct {
+	opt     EncodeOptions
+	buf     []byte
+	scratch encodeScratch
+
+	// line holds the point encoded by EncodeTo before it is copied into
+	// the bytes.Buffer.
+	line []byte
+}
+
+// NewEncoder creates a new Encoder that uses the options to encode points.
+func NewEncoder(opt EncodeOptions) *Encoder {
+	return &Encoder{opt: opt}
+}
+
+// encoderPool holds the encoders used by the line protocol.
+var encoderPool = sync.Pool{
+	New: func() interface{} { return &Encoder{} },
+}
+
+// Encode encodes the point and appends it to the internal buffer.
+func (e *Encoder) Encode(pt *Point) error {
+	var err error
+	e.buf, err = e.AppendPoint(e.buf, pt)
+	return err
+}
+
+// EncodeTo encodes the point directly into the bytes.Buffer.
+func (e *Encoder) EncodeTo(buf *bytes.Buffer, pt *Point) error {
+	b, err := e.AppendPoint(e.line[:0], pt)
+	if err != nil {
+		return err
+	}
+	e.line = b
+	buf.Write(b)
+	return nil
+}
+
+// Bytes returns the points encoded into the internal buffer. The slice is
+// only valid until the next call that modifies the Encoder.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// Len returns the number of bytes in the internal buffer.
+func (e *Encoder) Len() int {
+	return len(e.buf)
+}
+
+// Reset empties the internal buffer while keeping its capacity.
+func (e *Encoder) Reset() {
+	e.buf = e.buf[:0]
+}
+
+// WriteTo writes the internal buffer to the io.Writer. The buffer is reset
+// if all of it was written successfully.
+func (e *Encoder) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(e.buf)
+	if err == nil {
+		e.Reset()
+	}
+	return int64(n), err
+}
+
+// AppendPoint appends the encoded point to dst and returns the extended
+// buffer. If the point cannot be encoded, dst is returned unmodified along
+// with the error.
+func (e *Encoder) AppendPoint(dst []byte, pt *Point) ([]byte, error) {
+	start := len(dst)
+	dst, err := e.appendPoint(dst, pt)
+	if err != nil {
+		return dst[:start], err
+	}
+	return dst, nil
+}
+
+func (e *Encoder) appendPoint(dst []byte, pt *Point) ([]byte, error) {
+	if len(pt.Fields) == 0 {
+		return dst, ErrNoFields
+	}
+
+	tags := pt.Tags
+	if e.opt.Sorted {
+		defer e.scratch.reset()
+		tags = e.scratch.sortTags(tags)
+	}
+
+	if reason := checkIdentifier(pt.Name); reason != "" {
+		return dst, ErrInvalidPoint{Name: pt.Name, Reason: "measurement " + reason}
+	} else if pt.Name[0] == '#' {
+		return dst, ErrInvalidPoint{Name: pt.Name, Reason: "measurement begins with #"}
+	}
+	dst = appendEscaped(dst, pt.Name, measurementEscapeChars)
+	for _, t := range tags {
+		if t.Value == "" {
+			// The server does not accept empty tag values so the tag is
+			// left out of the point.
+			continue
+		} else if reason := checkIdentifier(t.Key); reason != "" {
+			return dst, ErrInvalidPoint{Name: pt.Name, Key: t.Key, Reason: "tag key " + reason}
+		} else if reason := checkIdentifier(t.Value); reason != "" {
+			return dst, ErrInvalidPoint{Name: pt.Name, Key: t.Key, Reason: "tag value " + reason}
+		}
+		dst = append(dst, ',')
+		dst = appendEscaped(dst, t.Key, tagEscapeChars)
+		dst = append(dst, '=')
+		dst = appendEscaped(dst, t.Value, tagEscapeChars)
+	}
+	dst = append(dst, ' ')
+
+	var (
+		n   int
+		err error
+	)
+	if e.opt.Sorted {
+		for _, k := range e.scratch.sortFieldKeys(pt.Fields) {
+			if dst, n, err = e.appendField(dst, pt.Name, n, k, pt.Fields[k]); err != nil {
+				return dst, err
+			}
+		}
+	} else {
+		for k, v := range pt.Fields {
+			if dst, n, err = e.appendField(dst, pt.Name, n, k, v); err != nil {
+				return dst, err
+			}
+		}
+	}
+	if n == 0 {
+		return dst, ErrNoFields
+	}
+
+	if !pt.Time.IsZero() {
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, pt.Time.UnixNano()/e.opt.Precision.factor(), 10)
+	}
+	return append(dst, '\n'), nil
+}
+
+// appendField appends a field to the buffer. The number of fields already
+// written is used to decide if a separator is needed and the new count is
+// returned. Nil values are omitted from the point.
+func (e *Encoder) appendField(dst []byte, name string, n int, k string, v interface{}) ([]byte, int, error) {
+	if reason := checkIdentifier(k); reason != "" {
+		return dst, n, ErrInvalidPoint{Name: name, Key: k, Reason: "field key " + reason}
+	}
+
+	mark := len(dst)
+	if n > 0 {
+		dst = append(dst, ',')
+	}
+	dst = appendEscaped(dst, k, fieldKeyEscapeChars)
+	dst = append(dst, '=')
+
+	dst, err := appendValue(dst, v, e.opt)
+	if err != nil {
+		if err == errNilValue {
+			return dst[:mark], n, nil
+		} else if ferr, ok := err.(ErrInvalidFloat); ok {
+			ferr.Field = k
+			return dst, n, ferr
+		}
+		return dst, n, err
+	}
+	return dst, n + 1, nil
+}
+
+// encodeScratch holds the buffers used to sort the tags and fields of a
+// point so they can be reused between points.
+type encodeScratch struct {
+	tags Tags
+	keys []string
+}
+
+// sortTags returns the tags sorted by key. If a key is repeated, the last
+// value for that key is kept. Tags that are already sorted and unique are
+// returned without being copied.
+func (s *encodeScratch) sortTags(tags Tags) Tags {
+	sorted := true
+	for i := 1; i < len(tags); i++ {
+		if tags[i-1].Key >= tags[i].Key {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		return tags
+	}
+
+	s.tags = append(s.tags[:0], tags...)
+	sort.Stable((*tagSorter)(s))
+
+	// Remove duplicates by keeping the last tag in each run of equal keys.
+	// The sort is stable so the last tag is the one the caller added last.
+	n := 0
+	for i, t := range s.tags {
+		if i+1 < len(s.tags) && s.tags[i+1].Key == t.Key {
+			continue
+		}
+		s.tags[n] = t
+		n++
+	}
+	s.tags = s.tags[:n]
+	return s.tags
+}
+
+// sortFieldKeys returns the keys of the fields in sorted order.
+func (s *encodeScratch) sortFieldKeys(fields map[string]interface{}) []string {
+	s.keys = s.keys[:0]
+	for k := range fields {
+		s.keys = append(s.keys, k)
+	}
+	sort.Sort((*keySorter)(s))
+	return s.keys
+}
+
+// reset clears the references held by the scratch buffers.
+func (s *encodeScratch) reset() {
+	for i := range s.tags {
+		s.tags[i] = Tag{}
+	}
+	for i := range s.keys {
+		s.keys[i] = ""
+	}
+	s.tags, s.keys = s.tags[:0], s.keys[:0]
+}
+
+// tagSorter and keySorter sort the scratch buffers through a pointer so
+// passing them to the sort package does not allocate.
+type (
+	tagSorter encodeScratch
+	keySorter encodeScratch
+)
+
+func (s *tagSorter) Len() int           { return len(s.tags) }
+func (s *tagSorter) Less(i, j int) bool { return s.tags[i].Key < s.tags[j].Key }
+func (s *tagSorter) Swap(i, j int)      { s.tags[i], s.tags[j] = s.tags[j], s.tags[i] }
+
+func (s *keySorter) Len() int           { return len(s.keys) }
+func (s *keySorter) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s *keySorter) Swap(i, j int)      { s.keys[i], s.keys[j] = s.keys[j], s.keys[i] }
@@ -0,0 +1,175 @@
+package influxdb_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func benchPoint() influxdb.Point {
+	return influxdb.Point{
+		Name: "cpu",
+		Tags: influxdb.Tags{
+			{Key: "host", Value: "server01"},
+			{Key: "region", Value: "us-west"},
+		},
+		Fields: map[string]interface{}{
+			"usage_user":   float64(12.5),
+			"usage_system": float64(3.25),
+			"processes":    int64(192),
+			"state":        "running",
+		},
+		Time: time.Unix(0, 1262304000000000000),
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	enc := influxdb.NewEncoder(influxdb.EncodeOptions{Sorted: true})
+
+	pt := influxdb.Point{
+		Name: "cpu load",
+		Tags: influxdb.Tags{{Key: "host", Value: "server 01"}},
+		Fields: map[string]interface{}{
+			"value":  float64(5),
+			"status": `say "hi"`,
+		},
+		Time: time.Unix(0, 1000),
+	}
+	if err := enc.Encode(&pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pt.Fields = map[string]interface{}{"value": float64(6)}
+	pt.Time = time.Time{}
+	if err := enc.Encode(&pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := "cpu\\ load,host=server\\ 01 status=\"say \\\"hi\\\"\",value=5 1000\ncpu\\ load,host=server\\ 01 value=6\n"
+	if got := string(enc.Bytes()); got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if n, err := enc.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if n != int64(len(want)) {
+		t.Fatalf("got %d bytes; want %d", n, len(want))
+	} else if got := buf.String(); got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	} else if enc.Len() != 0 {
+		t.Fatalf("expected the buffer to be reset after WriteTo, got %d bytes", enc.Len())
+	}
+}
+
+func TestEncoder_AppendPoint_Error(t *testing.T) {
+	enc := influxdb.NewEncoder(influxdb.EncodeOptions{})
+
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": []int{1}},
+	}
+	dst := []byte("mem free=1i\n")
+	got, err := enc.AppendPoint(dst, &pt)
+	if err == nil {
+		t.Fatal("expected error")
+	} else if string(got) != "mem free=1i\n" {
+		t.Fatalf("partial point was left in the buffer: %q", got)
+	}
+}
+
+func TestEncoder_EncodeTo(t *testing.T) {
+	enc := influxdb.NewEncoder(influxdb.EncodeOptions{Precision: influxdb.PrecisionSecond})
+
+	var buf bytes.Buffer
+	buf.WriteString("# points\n")
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": int64(1)},
+		Time:   time.Unix(10, 0),
+	}
+	if err := enc.EncodeTo(&buf, &pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if got, want := buf.String(), "# points\ncpu value=1i 10\n"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestEncoder_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not accurate with the race detector")
+	}
+
+	for _, opt := range []influxdb.EncodeOptions{{}, {Sorted: true}} {
+		enc := influxdb.NewEncoder(opt)
+		pt := benchPoint()
+		pt.Tags = append(pt.Tags, influxdb.Tag{Key: "path", Value: "C:\\Program Files"})
+
+		allocs := testing.AllocsPerRun(100, func() {
+			enc.Reset()
+			enc.Encode(&pt)
+		})
+		if allocs != 0 {
+			t.Errorf("sorted=%v: got %v allocations per point; want 0", opt.Sorted, allocs)
+		}
+	}
+}
+
+func BenchmarkEncoder_Encode(b *testing.B) {
+	enc := influxdb.NewEncoder(influxdb.EncodeOptions{})
+	pt := benchPoint()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		if err := enc.Encode(&pt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder_Encode_Sorted(b *testing.B) {
+	enc := influxdb.NewEncoder(influxdb.EncodeOptions{Sorted: true})
+	pt := benchPoint()
+	pt.Tags[0], pt.Tags[1] = pt.Tags[1], pt.Tags[0]
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		if err := enc.Encode(&pt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder_Encode_Escaped(b *testing.B) {
+	enc := influxdb.NewEncoder(influxdb.EncodeOptions{})
+	pt := benchPoint()
+	pt.Name = "cpu load"
+	pt.Tags[0].Value = "server 01,rack=2"
+	pt.Fields["state"] = `"running"`
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		if err := enc.Encode(&pt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLineProtocol_V1_Encode(b *testing.B) {
+	p := influxdb.LineProtocol.V1()
+	pt := benchPoint()
+
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := p.Encode(&buf, &pt, influxdb.EncodeOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
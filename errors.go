@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
 )
 
 var (
@@ -15,9 +19,24 @@ var (
 	// ErrNoFields is returned when attempting to write with no fields.
 	ErrNoFields = errors.New("no fields")
 
+	// ErrNoMeasurement is returned when attempting to write with no measurement name.
+	ErrNoMeasurement = errors.New("no measurement")
+
 	// ErrSeriesTruncated is returned when a series has been truncated and can
 	// no longer return more values.
 	ErrSeriesTruncated = errors.New("truncated output")
+
+	// ErrWriterClosed is returned when attempting to write to a writer that
+	// has been closed.
+	ErrWriterClosed = errors.New("writer closed")
+
+	// ErrSpoolFull is returned when a SpoolWriter does not have room for a
+	// write and the SpoolReject policy is used.
+	ErrSpoolFull = errors.New("spool full")
+
+	// ErrQueueFull is returned for a target of a MultiWriter when its queue
+	// is full.
+	ErrQueueFull = errors.New("queue full")
 )
 
 type ErrPing struct {
@@ -44,34 +63,333 @@ func (e ErrResult) Error() string {
 	return e.Err
 }
 
+// ErrPartialWrite is returned when the server wrote some of the points and
+// dropped the others.
 type ErrPartialWrite struct {
+	// Err is the error message from the server.
 	Err string
+
+	// Reason is the reason the server gave for dropping the points.
+	Reason PartialWriteReason
+
+	// Dropped is the number of points the server dropped.
+	Dropped int
+
+	// Lines are the line numbers of the written data that were dropped.
+	// Line numbers start at 1. This is only set when the dropped lines
+	// can be identified from the server message.
+	Lines []int
 }
 
 func (e ErrPartialWrite) Error() string {
 	return e.Err
 }
 
-// ReadError reads the HTTP response for an error and returns it.
-// It currently only supports errors sent back as JSON.
+// ErrWriteChunk is returned by Writer.ReadFrom when a chunk of the input
+// fails to write.
+type ErrWriteChunk struct {
+	// Offset is the byte offset in the input where the chunk starts.
+	Offset int64
+
+	// Line is the line number in the input where the chunk starts. Line
+	// numbers start at 1.
+	Line int
+
+	// Err is the error from writing the chunk. Any line numbers in an
+	// ErrPartialWrite are relative to the start of the chunk.
+	Err error
+}
+
+func (e ErrWriteChunk) Error() string {
+	return fmt.Sprintf("write failed at byte offset %d (line %d): %s", e.Offset, e.Line, e.Err)
+}
+
+// Unwrap returns the error from writing the chunk.
+func (e ErrWriteChunk) Unwrap() error {
+	return e.Err
+}
+
+// ErrMultiWrite is returned by a MultiWriter when too few targets wrote the
+// points to satisfy the consistency.
+type ErrMultiWrite struct {
+	// Errors holds the error for each target in the order the targets were
+	// given. It is nil for targets that succeeded or had not finished when
+	// the write returned.
+	Errors []error
+
+	// Written is the number of targets that wrote the points.
+	Written int
+
+	// Required is the number of targets that needed to write the points.
+	Required int
+}
+
+func (e ErrMultiWrite) Error() string {
+	var msgs []string
+	for i, err := range e.Errors {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("target %d: %s", i, err))
+		}
+	}
+	return fmt.Sprintf("wrote to %d of %d required targets: %s", e.Written, e.Required, strings.Join(msgs, "; "))
+}
+
+// Is reports whether the error from any target that failed matches the target
+// error. It lets errors.Is look inside the errors from the targets.
+func (e ErrMultiWrite) Is(target error) bool {
+	for _, err := range e.Errors {
+		if err != nil && errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error from a target that failed that matches the target
+// and sets the target to that error value. It lets errors.As look inside the
+// errors from the targets.
+func (e ErrMultiWrite) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if err != nil && errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrMultiWriteTarget is passed to the ErrorHandler of a MultiWriter when a
+// write to one of its targets fails.
+type ErrMultiWriteTarget struct {
+	// Index is the position of the target in the order the targets were given.
+	Index int
+
+	// Err is the error from writing to the target.
+	Err error
+}
+
+func (e ErrMultiWriteTarget) Error() string {
+	return fmt.Sprintf("target %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the error from writing to the target.
+func (e ErrMultiWriteTarget) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidFloat is returned when attempting to encode a field with a NaN or
+// infinite value. InfluxDB does not accept these values.
+type ErrInvalidFloat struct {
+	Field string
+	Value float64
+}
+
+func (e ErrInvalidFloat) Error() string {
+	return fmt.Sprintf("invalid value for field %q: %v", e.Field, e.Value)
+}
+
+// ErrInvalidPoint is returned when a point cannot be represented in the
+// line protocol.
+type ErrInvalidPoint struct {
+	// Name is the measurement name of the point.
+	Name string
+
+	// Key is the tag or field key that is invalid. It is empty if the
+	// measurement name is invalid.
+	Key string
+
+	// Reason describes why the point is invalid.
+	Reason string
+
+	// Index is the position of the point when it was validated as part of a
+	// batch of points.
+	Index int
+}
+
+func (e ErrInvalidPoint) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("invalid point %q: %s", e.Name, e.Reason)
+	}
+	return fmt.Sprintf("invalid point %q: key %q: %s", e.Name, e.Key, e.Reason)
+}
+
+// ErrValidation is returned when points fail validation. It contains an
+// error for each problem that was found.
+type ErrValidation struct {
+	Errors []error
+}
+
+func (e ErrValidation) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors for each problem that was found.
+func (e ErrValidation) Unwrap() []error {
+	return e.Errors
+}
+
+// ErrParse is returned when a line of the line protocol cannot be parsed.
+type ErrParse struct {
+	// Line is the line number where the point started. Line numbers start at 1.
+	Line int
+
+	// Text is the text of the point that could not be parsed.
+	Text string
+
+	// Reason describes why the point could not be parsed.
+	Reason string
+}
+
+func (e ErrParse) Error() string {
+	return fmt.Sprintf("unable to parse line %d: %s: %q", e.Line, e.Reason, e.Text)
+}
+
+// ErrScan is returned when a value cannot be scanned into a struct field.
+type ErrScan struct {
+	Field  string
+	Column string
+	Value  interface{}
+	Type   reflect.Type
+	Err    error
+}
+
+func (e ErrScan) Error() string {
+	return fmt.Sprintf("cannot scan %q (%T %v) into field %s of type %s: %s", e.Column, e.Value, e.Value, e.Field, e.Type, e.Err)
+}
+
+// Sentinel errors that an ErrHTTP matches with errors.Is depending on the
+// response from the server.
+var (
+	// ErrBadRequest matches a request the server rejected as malformed, such
+	// as a query that cannot be parsed.
+	ErrBadRequest = errors.New("bad request")
+
+	// ErrUnauthorized matches a request that failed authentication or
+	// authorization.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrDatabaseNotFound matches a request for a database that does not exist.
+	ErrDatabaseNotFound = errors.New("database not found")
+
+	// ErrTooManyRequests matches a request that was rejected because the
+	// client sent too many requests.
+	ErrTooManyRequests = errors.New("too many requests")
+
+	// ErrServerUnavailable matches a request that failed because the server
+	// or a proxy in front of it is overloaded or unavailable.
+	ErrServerUnavailable = errors.New("server unavailable")
+)
+
+// ErrHTTP is returned when the server responds with an error status code.
+type ErrHTTP struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// RequestID is the value of the Request-Id header.
+	RequestID string
+
+	// InfluxDBError is the value of the X-Influxdb-Error header.
+	InfluxDBError string
+
+	// Body is the raw body of the response.
+	Body []byte
+
+	// Code is the error code from an InfluxDB 2.x server, such as
+	// "invalid" or "not found".
+	Code string
+
+	// Message is the error message from the server.
+	Message string
+
+	// RetryAfter is the time to wait from the Retry-After header. It is zero
+	// if the header was not sent.
+	RetryAfter time.Duration
+}
+
+func (e ErrHTTP) Error() string {
+	return e.Message
+}
+
+// Is reports if the error matches one of the sentinel errors for the status
+// code and message of the response.
+func (e ErrHTTP) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrDatabaseNotFound:
+		return strings.HasPrefix(e.Message, "database not found") ||
+			(e.Code == "not found" && strings.HasPrefix(e.Message, "bucket"))
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerUnavailable:
+		switch e.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryable returns true if the error is temporary and the request may
+// succeed if it is sent again according to the DefaultRetryPolicy. This
+// includes network errors and responses with one of its retryable status
+// codes. Use RetryPolicy.IsRetryable to check an error against another policy.
+func IsRetryable(err error) bool {
+	return DefaultRetryPolicy.IsRetryable(err)
+}
+
+// ReadError reads the HTTP response for an error and returns it as an ErrHTTP.
+// It reads the message from JSON errors sent by both InfluxDB 1.x and 2.x
+// servers. Other responses use the body as the message.
 func ReadError(resp *http.Response) error {
-	out, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
+	var out []byte
+	body, err := responseBody(resp)
+	if err == nil {
+		out, err = ioutil.ReadAll(body)
+		body.Close()
+	}
+
+	e := ErrHTTP{
+		StatusCode:    resp.StatusCode,
+		RequestID:     resp.Header.Get("Request-Id"),
+		InfluxDBError: resp.Header.Get("X-Influxdb-Error"),
+		Body:          out,
+	}
+	e.RetryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"))
 	if err != nil || len(out) == 0 {
-		return fmt.Errorf("unknown http error: %s", resp.Status)
+		e.Message = fmt.Sprintf("unknown http error: %s", resp.Status)
+		if e.InfluxDBError != "" {
+			e.Message = e.InfluxDBError
+		}
+		return e
 	}
 
-	msg := string(out)
-	switch resp.Header.Get("Content-Type") {
-	case "application/json":
+	e.Message = string(out)
+	if mediatype, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediatype == "application/json" {
+		// InfluxDB 1.x sends the message in error and 2.x sends a code
+		// and message.
 		var jsonErr struct {
-			Error string `json:"error"`
+			Error   string `json:"error"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
 		}
 		if err := json.Unmarshal(out, &jsonErr); err == nil {
 			// Ignore any errors from parsing the JSON from the server.
 			// The server may have just sent a bad message and we don't want to mask that.
-			msg = jsonErr.Error
+			e.Message = jsonErr.Error
+			if jsonErr.Message != "" || jsonErr.Code != "" {
+				e.Code, e.Message = jsonErr.Code, jsonErr.Message
+			}
 		}
 	}
-	return errors.New(msg)
+	return e
 }
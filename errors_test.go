@@ -0,0 +1,174 @@
+package influxdb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func newErrorResponse(code int, contentType, body string) *http.Response {
+	resp := &http.Response{
+		StatusCode: code,
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+	if contentType != "" {
+		resp.Header.Set("Content-Type", contentType)
+	}
+	return resp
+}
+
+func TestReadError(t *testing.T) {
+	resp := newErrorResponse(http.StatusNotFound, "application/json", `{"error":"database not found: \"db0\""}`)
+	resp.Header.Set("Request-Id", "f3e2d9a0")
+	resp.Header.Set("X-Influxdb-Error", `database not found: "db0"`)
+
+	err := influxdb.ReadError(resp)
+	httpErr, ok := err.(influxdb.ErrHTTP)
+	if !ok {
+		t.Fatalf("got error %#v; want %T", err, influxdb.ErrHTTP{})
+	}
+
+	if got, want := httpErr.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("StatusCode = %d; want %d", got, want)
+	}
+	if got, want := httpErr.RequestID, "f3e2d9a0"; got != want {
+		t.Errorf("RequestID = %q; want %q", got, want)
+	}
+	if got, want := httpErr.InfluxDBError, `database not found: "db0"`; got != want {
+		t.Errorf("InfluxDBError = %q; want %q", got, want)
+	}
+	if got, want := string(httpErr.Body), `{"error":"database not found: \"db0\""}`; got != want {
+		t.Errorf("Body = %q; want %q", got, want)
+	}
+	if got, want := err.Error(), `database not found: "db0"`; got != want {
+		t.Errorf("Error() = %q; want %q", got, want)
+	}
+}
+
+func TestReadError_V2(t *testing.T) {
+	resp := newErrorResponse(http.StatusNotFound, "application/json; charset=utf-8", `{"code":"not found","message":"bucket \"telegraf\" not found"}`)
+
+	err := influxdb.ReadError(resp)
+	httpErr, ok := err.(influxdb.ErrHTTP)
+	if !ok {
+		t.Fatalf("got error %#v; want %T", err, influxdb.ErrHTTP{})
+	}
+
+	if got, want := httpErr.Code, "not found"; got != want {
+		t.Errorf("Code = %q; want %q", got, want)
+	}
+	if got, want := err.Error(), `bucket "telegraf" not found`; got != want {
+		t.Errorf("Error() = %q; want %q", got, want)
+	}
+	if !errors.Is(err, influxdb.ErrDatabaseNotFound) {
+		t.Errorf("errors.Is(%v, ErrDatabaseNotFound) = false; want true", err)
+	}
+}
+
+func TestReadError_EmptyBody(t *testing.T) {
+	err := influxdb.ReadError(newErrorResponse(http.StatusServiceUnavailable, "", ""))
+	if got, want := err.Error(), "unknown http error: 503 Service Unavailable"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestErrHTTP_Is(t *testing.T) {
+	sentinels := []error{
+		influxdb.ErrBadRequest,
+		influxdb.ErrUnauthorized,
+		influxdb.ErrDatabaseNotFound,
+		influxdb.ErrTooManyRequests,
+		influxdb.ErrServerUnavailable,
+	}
+	for _, tt := range []struct {
+		code int
+		body string
+		want error
+	}{
+		{code: http.StatusBadRequest, body: "error parsing query", want: influxdb.ErrBadRequest},
+		{code: http.StatusUnauthorized, body: "authorization failed", want: influxdb.ErrUnauthorized},
+		{code: http.StatusForbidden, body: "forbidden", want: influxdb.ErrUnauthorized},
+		{code: http.StatusNotFound, body: `database not found: "db0"`, want: influxdb.ErrDatabaseNotFound},
+		{code: http.StatusTooManyRequests, body: "slow down", want: influxdb.ErrTooManyRequests},
+		{code: http.StatusServiceUnavailable, body: "overloaded", want: influxdb.ErrServerUnavailable},
+		{code: http.StatusBadGateway, body: "bad gateway", want: influxdb.ErrServerUnavailable},
+	} {
+		err := fmt.Errorf("write failed: %w", influxdb.ReadError(newErrorResponse(tt.code, "text/plain", tt.body)))
+		for _, sentinel := range sentinels {
+			if got, want := errors.Is(err, sentinel), sentinel == tt.want; got != want {
+				t.Errorf("%d %s: errors.Is(err, %v) = %v; want %v", tt.code, tt.body, sentinel, got, want)
+			}
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RetryPolicy = &influxdb.RetryPolicy{MaxAttempts: 1}
+	_, netErr := client.Writer().Write([]byte("cpu value=1\n"))
+	if netErr == nil {
+		t.Fatal("expected a network error")
+	}
+
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("unknown"), want: false},
+		{err: influxdb.ReadError(newErrorResponse(http.StatusServiceUnavailable, "", "")), want: true},
+		{err: influxdb.ReadError(newErrorResponse(http.StatusTooManyRequests, "", "")), want: true},
+		{err: influxdb.ReadError(newErrorResponse(http.StatusBadRequest, "", "bad query")), want: false},
+		{err: netErr, want: true},
+		{err: context.Canceled, want: false},
+		{err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: false},
+	} {
+		if got := influxdb.IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_IsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, netErr := client.Writer().Write([]byte("cpu value=1\n"))
+	if netErr == nil {
+		t.Fatal("expected a network error")
+	}
+
+	p := &influxdb.RetryPolicy{RetryableStatusCodes: []int{http.StatusInternalServerError}}
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{err: influxdb.ReadError(newErrorResponse(http.StatusInternalServerError, "", "")), want: true},
+		{err: influxdb.ReadError(newErrorResponse(http.StatusServiceUnavailable, "", "")), want: false},
+		{err: netErr, want: false},
+		{err: context.Canceled, want: false},
+	} {
+		if got := p.IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
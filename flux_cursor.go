@@ -0,0 +1,458 @@
+package influxdb
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FluxColumn describes a column in the annotated CSV response of a Flux query.
+type FluxColumn struct {
+	// Name is the name of the column.
+	Name string
+
+	// Datatype is the type from the #datatype annotation, such as "long" or
+	// "dateTime:RFC3339".
+	Datatype string
+
+	// Group is true if the column is part of the group key from the #group
+	// annotation.
+	Group bool
+
+	// Default is the value from the #default annotation that is used when a
+	// row has no value for the column. It is nil if there is no default.
+	Default interface{}
+}
+
+// FluxResultSet is a ResultSet for a Flux query. Each ResultSet contains the
+// tables that share the same columns and annotations. The tables from a
+// single Flux result may be split between multiple ResultSets if their
+// columns are different.
+type FluxResultSet interface {
+	ResultSet
+
+	// FluxColumns returns the annotations for each column in the same order
+	// as Columns.
+	FluxColumns() []FluxColumn
+}
+
+// FluxSeries is a Series for a table in the response of a Flux query. The
+// Tags are the group key columns other than result, table and _measurement.
+// The Name is the value of the _measurement column if it is in the group key.
+type FluxSeries interface {
+	Series
+
+	// Result returns the name of the result the table belongs to.
+	Result() string
+
+	// Table returns the table number within the result.
+	Table() int64
+}
+
+// fluxCursor reads the annotated CSV output of a Flux query.
+//
+// The output is split into blocks. Each block starts with the annotation
+// rows, which begin with a #, followed by a header row and the data rows. The
+// first column of every row is reserved for the annotations and is empty for
+// the header and data rows. Consecutive rows with the same result and table
+// belong to the same table.
+type fluxCursor struct {
+	r  io.ReadCloser
+	cr *csv.Reader
+
+	// next holds a record that has been read, but not consumed yet.
+	next []string
+	err  error
+
+	// started is set once the first block has been read. A header without
+	// annotations is only allowed at the start of the output.
+	started bool
+
+	cur *fluxResult
+}
+
+func newFluxCursor(r io.ReadCloser) *fluxCursor {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	return &fluxCursor{
+		r:  r,
+		cr: cr,
+	}
+}
+
+// read returns the next record from the stream.
+func (c *fluxCursor) read() ([]string, error) {
+	if c.next != nil {
+		rec := c.next
+		c.next = nil
+		return rec, nil
+	} else if c.err != nil {
+		return nil, c.err
+	}
+
+	rec, err := c.cr.Read()
+	if err != nil {
+		c.err = err
+		return nil, err
+	}
+	return rec, nil
+}
+
+// unread pushes the record back so it is returned by the next call to read.
+func (c *fluxCursor) unread(rec []string) {
+	c.next = rec
+}
+
+func (c *fluxCursor) NextSet() (ResultSet, error) {
+	if c.cur != nil {
+		// Invalidate the current result so it stops reading from the cursor.
+		c.cur.cur = nil
+		c.cur = nil
+	}
+
+	// Skip to the annotations for the next block.
+	var datatypes, groups, defaults []string
+	header := !c.started
+	for {
+		rec, err := c.read()
+		if err != nil {
+			if err == io.EOF && header && c.started {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if isFluxAnnotation(rec) {
+			switch rec[0] {
+			case "#datatype":
+				datatypes = rec[1:]
+			case "#group":
+				groups = rec[1:]
+			case "#default":
+				defaults = rec[1:]
+			}
+			header = true
+			c.started = true
+			continue
+		} else if !header {
+			// This is a remaining row of the previous block.
+			continue
+		}
+		c.started = true
+
+		columns := rec[1:]
+		if len(columns) >= 1 && columns[0] == "error" {
+			msg, err := c.read()
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			} else if len(msg) < 2 {
+				return nil, ErrResult{Err: "unknown error"}
+			}
+			return nil, ErrResult{Err: msg[1]}
+		}
+
+		r := &fluxResult{
+			columns:     columns,
+			fluxColumns: make([]FluxColumn, len(columns)),
+			cur:         c,
+		}
+		for i, name := range columns {
+			col := FluxColumn{Name: name, Datatype: "string"}
+			if i < len(datatypes) {
+				col.Datatype = datatypes[i]
+			}
+			if i < len(groups) {
+				col.Group = groups[i] == "true"
+			}
+			if i < len(defaults) && defaults[i] != "" {
+				v, err := parseFluxValue(defaults[i], col.Datatype)
+				if err != nil {
+					return nil, fmt.Errorf("invalid default for column %q: %s", name, err)
+				}
+				col.Default = v
+			}
+			r.fluxColumns[i] = col
+		}
+		r.resultIndex = r.Index("result")
+		r.tableIndex = r.Index("table")
+		c.cur = r
+		return r, nil
+	}
+}
+
+func (c *fluxCursor) Close() error {
+	if err := c.r.Close(); err != nil {
+		return err
+	}
+	c.next = nil
+	return nil
+}
+
+// isFluxAnnotation returns true if the record is an annotation row.
+func isFluxAnnotation(rec []string) bool {
+	return len(rec) > 0 && strings.HasPrefix(rec[0], "#")
+}
+
+type fluxResult struct {
+	columns       []string
+	fluxColumns   []FluxColumn
+	columnsByName map[string]int
+	resultIndex   int
+	tableIndex    int
+	cur           *fluxCursor
+	series        *fluxSeries
+}
+
+func (r *fluxResult) Columns() []string {
+	return r.columns
+}
+
+func (r *fluxResult) FluxColumns() []FluxColumn {
+	return r.fluxColumns
+}
+
+func (r *fluxResult) Index(name string) int {
+	if r.columnsByName == nil {
+		r.columnsByName = make(map[string]int, len(r.columns))
+		for i, s := range r.columns {
+			if _, ok := r.columnsByName[s]; !ok {
+				r.columnsByName[s] = i
+			}
+		}
+	}
+
+	if i, ok := r.columnsByName[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// Messages always returns nil because Flux does not send informational
+// messages.
+func (r *fluxResult) Messages() []*Message {
+	return nil
+}
+
+func (r *fluxResult) NextSeries() (Series, error) {
+	if r.cur == nil {
+		return nil, io.EOF
+	}
+
+	// Discard the remaining rows in the current table.
+	if r.series != nil {
+		for {
+			if _, err := r.series.NextRow(); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, err
+			}
+		}
+		r.series.invalid = true
+		r.series = nil
+	}
+
+	rec, err := r.cur.read()
+	if err != nil {
+		return nil, err
+	} else if isFluxAnnotation(rec) {
+		// This is the start of the next ResultSet.
+		r.cur.unread(rec)
+		return nil, io.EOF
+	}
+	r.cur.unread(rec)
+
+	values, err := r.parseRow(rec)
+	if err != nil {
+		return nil, err
+	}
+	s := &fluxSeries{
+		key: r.tableKey(rec),
+		r:   r,
+	}
+	if r.resultIndex >= 0 {
+		s.result, _ = values[r.resultIndex].(string)
+	}
+	if r.tableIndex >= 0 {
+		s.table, _ = values[r.tableIndex].(int64)
+	}
+	for i, col := range r.fluxColumns {
+		if !col.Group || i == r.resultIndex || i == r.tableIndex {
+			continue
+		}
+		var value string
+		if i+1 < len(rec) {
+			value = rec[i+1]
+		}
+		if value == "" && col.Default != nil {
+			value = fmt.Sprint(col.Default)
+		}
+		if col.Name == "_measurement" {
+			s.name = value
+			continue
+		}
+		s.tags = append(s.tags, Tag{Key: col.Name, Value: value})
+	}
+	sort.Slice(s.tags, func(i, j int) bool { return s.tags[i].Key < s.tags[j].Key })
+	r.series = s
+	return s, nil
+}
+
+// tableKey returns the raw result and table columns of the record. Rows with
+// the same key belong to the same table.
+func (r *fluxResult) tableKey(rec []string) [2]string {
+	var key [2]string
+	if r.resultIndex >= 0 && r.resultIndex+1 < len(rec) {
+		key[0] = rec[r.resultIndex+1]
+	}
+	if r.tableIndex >= 0 && r.tableIndex+1 < len(rec) {
+		key[1] = rec[r.tableIndex+1]
+	}
+	return key
+}
+
+// parseRow converts the values in the record to the type of each column.
+func (r *fluxResult) parseRow(rec []string) ([]interface{}, error) {
+	values := make([]interface{}, len(r.fluxColumns))
+	for i, col := range r.fluxColumns {
+		if i+1 >= len(rec) || rec[i+1] == "" {
+			values[i] = col.Default
+			continue
+		}
+
+		v, err := parseFluxValue(rec[i+1], col.Datatype)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for column %q: %s", col.Name, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+type fluxSeries struct {
+	name   string
+	tags   Tags
+	result string
+	table  int64
+	key    [2]string
+	sz     int
+
+	r        *fluxResult
+	complete bool
+	invalid  bool
+}
+
+func (s *fluxSeries) Name() string {
+	return s.name
+}
+
+func (s *fluxSeries) Tags() Tags {
+	return s.tags
+}
+
+func (s *fluxSeries) Columns() []string {
+	return s.r.Columns()
+}
+
+func (s *fluxSeries) Result() string {
+	return s.result
+}
+
+func (s *fluxSeries) Table() int64 {
+	return s.table
+}
+
+// Len returns the number of rows read so far. The output does not contain
+// the length of a table so the table is only known to be complete after the
+// last row has been read.
+func (s *fluxSeries) Len() (n int, complete bool) {
+	return s.sz, s.complete
+}
+
+func (s *fluxSeries) NextRow() (Row, error) {
+	if s.complete || s.invalid || s.r.cur == nil {
+		return nil, io.EOF
+	}
+
+	rec, err := s.r.cur.read()
+	if err != nil {
+		if err == io.EOF {
+			s.complete = true
+		}
+		return nil, err
+	} else if isFluxAnnotation(rec) || s.r.tableKey(rec) != s.key {
+		// This row belongs to the next table or the next result.
+		s.r.cur.unread(rec)
+		s.complete = true
+		return nil, io.EOF
+	}
+
+	values, err := s.r.parseRow(rec)
+	if err != nil {
+		return nil, err
+	}
+	s.sz++
+	return fluxRow{values: values, result: s.r}, nil
+}
+
+type fluxRow struct {
+	values []interface{}
+	result *fluxResult
+}
+
+// Time returns the value of the _time column.
+func (r fluxRow) Time() time.Time {
+	t, _ := r.ValueByName("_time").(time.Time)
+	return t
+}
+
+func (r fluxRow) Value(index int) interface{} {
+	return r.values[index]
+}
+
+func (r fluxRow) Values() []interface{} {
+	return r.values
+}
+
+func (r fluxRow) ValueByName(column string) interface{} {
+	index := r.result.Index(column)
+	if index == -1 || index >= len(r.values) {
+		return nil
+	}
+	return r.values[index]
+}
+
+// parseFluxValue converts a value from the annotated CSV output to the Go
+// type for the datatype. Unknown datatypes are returned as strings.
+func parseFluxValue(s, datatype string) (interface{}, error) {
+	switch datatype {
+	case "long":
+		return strconv.ParseInt(s, 10, 64)
+	case "unsignedLong":
+		return strconv.ParseUint(s, 10, 64)
+	case "double":
+		return strconv.ParseFloat(s, 64)
+	case "boolean":
+		return strconv.ParseBool(s)
+	case "dateTime", "dateTime:RFC3339", "dateTime:RFC3339Nano":
+		return time.Parse(time.RFC3339Nano, s)
+	case "duration":
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		return time.Duration(n), err
+	case "base64Binary":
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return s, nil
+	}
+}
@@ -0,0 +1,169 @@
+package influxdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+const fluxResponse = `#datatype,string,long,dateTime:RFC3339,double,string,string,string
+#group,false,false,false,false,true,true,true
+#default,_result,,,,,,
+,result,table,_time,_value,_field,_measurement,host
+,,0,2020-01-01T00:00:00Z,1.5,usage,cpu,server01
+,,0,2020-01-01T00:00:10Z,2.5,usage,cpu,server01
+,,1,2020-01-01T00:00:00Z,3,usage,cpu,server02
+
+#datatype,string,long,dateTime:RFC3339,long,boolean,unsignedLong,duration,base64Binary,string
+#group,false,false,false,false,false,false,false,false,true
+#default,counts,,,,,,,,
+,result,table,_time,_value,ok,size,elapsed,raw,_field
+,,0,2020-01-01T00:00:00.5Z,7,true,18446744073709551615,1h30m,aGk=,count
+,,0,2020-01-01T00:00:01Z,,false,0,10ns,,count
+`
+
+func TestCursor_Flux(t *testing.T) {
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(strings.NewReader(fluxResponse)), "flux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := result.Columns(), []string{"result", "table", "_time", "_value", "_field", "_measurement", "host"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Columns() = %q; want %q", got, want)
+	}
+
+	columns := result.(influxdb.FluxResultSet).FluxColumns()
+	if got, want := columns[0], (influxdb.FluxColumn{Name: "result", Datatype: "string", Default: "_result"}); got != want {
+		t.Errorf("FluxColumns()[0] = %#v; want %#v", got, want)
+	}
+	if got, want := columns[6], (influxdb.FluxColumn{Name: "host", Datatype: "string", Group: true}); got != want {
+		t.Errorf("FluxColumns()[6] = %#v; want %#v", got, want)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := series.Name(), "cpu"; got != want {
+		t.Errorf("Name() = %q; want %q", got, want)
+	}
+	if got, want := series.Tags(), (influxdb.Tags{{Key: "_field", Value: "usage"}, {Key: "host", Value: "server01"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v; want %v", got, want)
+	}
+	if got, want := series.(influxdb.FluxSeries).Result(), "_result"; got != want {
+		t.Errorf("Result() = %q; want %q", got, want)
+	}
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"_result", int64(0), mustParseTime("2020-01-01T00:00:00Z"), 1.5, "usage", "cpu", "server01"}
+	if got := row.Values(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Values() = %#v; want %#v", got, want)
+	}
+	if got, want := row.Time(), mustParseTime("2020-01-01T00:00:00Z"); !got.Equal(want) {
+		t.Errorf("Time() = %v; want %v", got, want)
+	}
+
+	// Skipping the rest of the first table moves on to the second table.
+	series, err = result.NextSeries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := series.(influxdb.FluxSeries).Table(), int64(1); got != want {
+		t.Errorf("Table() = %d; want %d", got, want)
+	}
+	if got, want := series.Tags()[1].Value, "server02"; got != want {
+		t.Errorf("host = %q; want %q", got, want)
+	}
+	if _, err := result.NextSeries(); err != io.EOF {
+		t.Fatalf("NextSeries() = %v; want %v", err, io.EOF)
+	}
+
+	result, err = cur.NextSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err = result.NextSeries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := series.Name(), ""; got != want {
+		t.Errorf("Name() = %q; want %q", got, want)
+	}
+	if got, want := series.(influxdb.FluxSeries).Result(), "counts"; got != want {
+		t.Errorf("Result() = %q; want %q", got, want)
+	}
+
+	var rows [][]interface{}
+	if err := influxdb.EachRow(series, func(row influxdb.Row) error {
+		rows = append(rows, row.Values())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][]interface{}{
+		{"counts", int64(0), mustParseTime("2020-01-01T00:00:00.5Z"), int64(7), true, uint64(math.MaxUint64), 90 * time.Minute, []byte("hi"), "count"},
+		{"counts", int64(0), mustParseTime("2020-01-01T00:00:01Z"), nil, false, uint64(0), 10 * time.Nanosecond, nil, "count"},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Fatalf("rows = %#v; want %#v", rows, wantRows)
+	}
+
+	if _, err := cur.NextSet(); err != io.EOF {
+		t.Fatalf("NextSet() = %v; want %v", err, io.EOF)
+	}
+}
+
+func TestCursor_Flux_Error(t *testing.T) {
+	r := strings.NewReader(`#datatype,string,string
+#group,true,true
+#default,,
+,error,reference
+,"failed to execute query: bucket not found",
+`)
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(r), "flux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	_, err = cur.NextSet()
+	if got, want := err, (influxdb.ErrResult{Err: "failed to execute query: bucket not found"}); got != want {
+		t.Fatalf("err = %v; want %v", got, want)
+	}
+}
+
+func TestCursor_Flux_InvalidValue(t *testing.T) {
+	r := strings.NewReader(`#datatype,string,long,long
+#group,false,false,false
+#default,_result,,
+,result,table,_value
+,,0,abc
+`)
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(r), "flux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := result.NextSeries(); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,158 @@
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// FluxQueryOptions is a set of configuration options for configuring Flux
+// queries.
+type FluxQueryOptions struct {
+	// Org is the name or ID of the organization to query.
+	Org string
+
+	// Params are the parameters of the query. They are available to the
+	// Flux script in the params record.
+	Params map[string]interface{}
+
+	// Now is the time used as now() by the query. If this is zero, the
+	// server uses the current time.
+	Now time.Time
+
+	// Compression asks the server to compress the response. The response
+	// is decompressed as it is read by the Cursor.
+	Compression Compression
+}
+
+// Clone creates a copy of the FluxQueryOptions.
+func (opt *FluxQueryOptions) Clone() FluxQueryOptions {
+	clone := *opt
+	clone.Params = make(map[string]interface{})
+	for k, v := range opt.Params {
+		clone.Params[k] = v
+	}
+	return clone
+}
+
+// FluxQuerier holds onto Flux query options and acts as a convenience method
+// for performing Flux queries against the /api/v2/query endpoint of an
+// InfluxDB 2.x server.
+type FluxQuerier struct {
+	c *Client
+	FluxQueryOptions
+}
+
+// FluxQuerier returns a struct that can be used to save Flux query options and
+// execute Flux queries.
+func (c *Client) FluxQuerier() *FluxQuerier {
+	return &FluxQuerier{c: c}
+}
+
+// fluxDialect is the dialect requested for every Flux query. The annotations
+// are needed to decode the values with the correct types.
+var fluxDialect = map[string]interface{}{
+	"header":         true,
+	"delimiter":      ",",
+	"annotations":    []string{"datatype", "group", "default"},
+	"commentPrefix":  "#",
+	"dateTimeFormat": "RFC3339Nano",
+}
+
+// Query executes the Flux script and returns a Cursor that will parse the
+// annotated CSV results from the stream. Each Series returned by the Cursor is
+// a Flux table and implements FluxSeries. Each ResultSet implements
+// FluxResultSet. The Param and Params options set the parameters of the query.
+// Any other QueryOption returns an error.
+//
+// A Flux script can write data with to(), so failed queries are only retried
+// if the RetryPolicy allows retrying non-idempotent requests.
+func (q *FluxQuerier) Query(query string, opts ...QueryOption) (Cursor, error) {
+	return q.QueryContext(context.Background(), query, opts...)
+}
+
+// QueryContext executes the Flux script like Query. If the context is
+// cancelled, the request is aborted and any in-progress read from the Cursor
+// returns the context error.
+func (q *FluxQuerier) QueryContext(ctx context.Context, query string, opts ...QueryOption) (Cursor, error) {
+	opt := q.FluxQueryOptions
+	if len(opts) > 0 {
+		opt = opt.Clone()
+
+		// The options are written for QueryOptions so they are applied to
+		// an empty QueryOptions and only the parameters are taken from it.
+		// Any other option has no meaning for a Flux query.
+		qopt := QueryOptions{Params: opt.Params}
+		for _, f := range opts {
+			f.apply(&qopt)
+		}
+		if !reflect.DeepEqual(qopt, QueryOptions{Params: qopt.Params}) {
+			return nil, errors.New("only the Param and Params query options are supported by Flux queries")
+		}
+		opt.Params = qopt.Params
+	}
+
+	req, err := q.c.newFluxQueryRequest(ctx, query, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.c.do(req, false)
+	if err != nil {
+		return nil, err
+	} else if resp.StatusCode/100 != 2 {
+		return nil, ReadError(resp)
+	}
+	body, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	return newFluxCursor(newContextReader(ctx, body)), nil
+}
+
+// newFluxQueryRequest creates a new HTTP request for the Flux query. The
+// request is bound to the context.
+func (c *Client) newFluxQueryRequest(ctx context.Context, query string, opt FluxQueryOptions) (*http.Request, error) {
+	body := map[string]interface{}{
+		"query":   query,
+		"type":    "flux",
+		"dialect": fluxDialect,
+	}
+	if len(opt.Params) > 0 {
+		body["params"] = opt.Params
+	}
+	if !opt.Now.IsZero() {
+		body["now"] = opt.Now.Format(time.RFC3339Nano)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	if opt.Org != "" {
+		values.Set("org", opt.Org)
+	}
+	u := c.url("/api/v2/query")
+	u.RawQuery = values.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+	c.Auth.setAuth(req)
+
+	if err := opt.Compression.setAcceptEncoding(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,148 @@
+package influxdb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func TestFluxQuerier_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Method, "POST"; got != want {
+			t.Errorf("Method = %q; want %q", got, want)
+		}
+		if got, want := r.URL.Path, "/api/v2/query"; got != want {
+			t.Errorf("Path = %q; want %q", got, want)
+		}
+		if got, want := r.URL.Query().Get("org"), "my-org"; got != want {
+			t.Errorf("org = %q; want %q", got, want)
+		}
+		if got, want := r.Header.Get("Authorization"), "Token my-token"; got != want {
+			t.Errorf("Authorization = %q; want %q", got, want)
+		}
+		if got, want := r.Header.Get("Content-Type"), "application/json"; got != want {
+			t.Errorf("Content-Type = %q; want %q", got, want)
+		}
+
+		var body struct {
+			Query   string                 `json:"query"`
+			Type    string                 `json:"type"`
+			Params  map[string]interface{} `json:"params"`
+			Now     string                 `json:"now"`
+			Dialect struct {
+				Header      bool     `json:"header"`
+				Annotations []string `json:"annotations"`
+			} `json:"dialect"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("unexpected error decoding body: %s", err)
+		}
+		if got, want := body.Query, `from(bucket: params.bucket) |> range(start: -1h)`; got != want {
+			t.Errorf("query = %q; want %q", got, want)
+		}
+		if got, want := body.Type, "flux"; got != want {
+			t.Errorf("type = %q; want %q", got, want)
+		}
+		if got, want := body.Params, map[string]interface{}{"bucket": "telegraf"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("params = %v; want %v", got, want)
+		}
+		if got, want := body.Now, "2020-01-01T00:00:00Z"; got != want {
+			t.Errorf("now = %q; want %q", got, want)
+		}
+		if got, want := body.Dialect.Annotations, []string{"datatype", "group", "default"}; !body.Dialect.Header || !reflect.DeepEqual(got, want) {
+			t.Errorf("dialect annotations = %q; want %q", got, want)
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		io.WriteString(w, fluxResponse)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Auth = &influxdb.Auth{Token: "my-token"}
+
+	querier := client.FluxQuerier()
+	querier.Org = "my-org"
+	querier.Now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cur, err := querier.Query(`from(bucket: params.bucket) |> range(start: -1h)`, influxdb.Param("bucket", "telegraf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	results := readAll(t, cur)
+	if got, want := len(results), 2; got != want {
+		t.Fatalf("got %d results; want %d", got, want)
+	}
+	if got, want := len(querier.Params), 0; got != want {
+		t.Errorf("querier params = %d; want %d", got, want)
+	}
+}
+
+func TestFluxQuerier_Query_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"code":"invalid","message":"compilation failed: error at @1:1-1:5: undefined identifier frm"}`)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.FluxQuerier().Query(`frm(bucket: "telegraf")`)
+	var httpErr influxdb.ErrHTTP
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v; want %T", err, httpErr)
+	}
+	if got, want := httpErr.Code, "invalid"; got != want {
+		t.Errorf("Code = %q; want %q", got, want)
+	}
+	if !errors.Is(err, influxdb.ErrBadRequest) {
+		t.Errorf("errors.Is(%v, ErrBadRequest) = false; want true", err)
+	}
+}
+
+func TestFluxQuerier_Query_Retry(t *testing.T) {
+	for _, tt := range []struct {
+		retryNonIdempotent bool
+		attempts           int32
+	}{
+		{retryNonIdempotent: false, attempts: 1},
+		{retryNonIdempotent: true, attempts: 2},
+	} {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				http.Error(w, "overloaded", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			io.WriteString(w, fluxResponse)
+		}))
+
+		client := newRetryClient(t, server.URL)
+		client.RetryPolicy.RetryNonIdempotent = tt.retryNonIdempotent
+		cur, err := client.FluxQuerier().Query(`from(bucket: "telegraf") |> range(start: -1h)`)
+		if err == nil {
+			cur.Close()
+		}
+		if got, want := atomic.LoadInt32(&attempts), tt.attempts; got != want {
+			t.Errorf("RetryNonIdempotent %v: attempts = %d; want %d", tt.retryNonIdempotent, got, want)
+		}
+		server.Close()
+	}
+}
@@ -4,23 +4,41 @@ import (
 	"encoding/json"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// jsonCursor reads the results from the server one response at a time.
+//
+// The msgpack output uses the same structure as the JSON output so this
+// cursor is also used for msgpack with a different resultDecoder.
 type jsonCursor struct {
 	r   io.ReadCloser
-	dec *json.Decoder
+	dec resultDecoder
 
 	cur *jsonResult
-	buf struct {
-		Results []*jsonResult `json:"results"`
-	}
+	buf jsonResponse
+}
+
+// resultDecoder decodes the next response from the stream into a *jsonResponse.
+type resultDecoder interface {
+	Decode(v interface{}) error
+}
+
+// jsonResponse is a single response from the server. When the output is
+// chunked, the server sends multiple responses.
+type jsonResponse struct {
+	Results []*jsonResult `json:"results"`
 }
 
 func newJSONCursor(r io.ReadCloser) *jsonCursor {
+	// Decode numbers as json.Number so integers are not rounded to a float64.
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
 	return &jsonCursor{
 		r:   r,
-		dec: json.NewDecoder(r),
+		dec: dec,
 	}
 }
 
@@ -93,16 +111,10 @@ func (c *jsonCursor) Hijack() (io.ReadCloser, error) {
 }
 
 type jsonResult struct {
-	Series []struct {
-		Name    string            `json:"name"`
-		Tags    map[string]string `json:"tags"`
-		Columns []string          `json:"columns"`
-		Values  [][]interface{}   `json:"values"`
-		Partial bool              `json:"partial"`
-	} `json:"series"`
-	MessageList []*Message `json:"messages"`
-	Partial     bool       `json:"partial"`
-	Err         string     `json:"error"`
+	Series      []jsonRawSeries `json:"series"`
+	MessageList []*Message      `json:"messages"`
+	Partial     bool            `json:"partial"`
+	Err         string          `json:"error"`
 
 	index         int
 	columns       []string
@@ -111,6 +123,15 @@ type jsonResult struct {
 	series        *jsonSeries
 }
 
+// jsonRawSeries is a series as it is sent by the server.
+type jsonRawSeries struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags"`
+	Columns []string          `json:"columns"`
+	Values  [][]interface{}   `json:"values"`
+	Partial bool              `json:"partial"`
+}
+
 // Columns returns the columns for this result.
 //
 // Columns is just a gigantic mistake in the JSON output for InfluxDB. Columns
@@ -355,9 +376,32 @@ func (s *jsonSeries) NextRow() (Row, error) {
 
 	v := s.values[0]
 	s.values = s.values[1:]
+	convertNumbers(v)
 	return jsonRow{values: v, result: s.r}, nil
 }
 
+// convertNumbers replaces any json.Number in the values with an int64 if the
+// server sent an integer literal and a float64 otherwise.
+func convertNumbers(values []interface{}) {
+	for i, v := range values {
+		if n, ok := v.(json.Number); ok {
+			values[i] = parseNumber(string(n))
+		}
+	}
+}
+
+// parseNumber parses a JSON number literal without losing precision. An
+// integer literal that does not fit in an int64 is returned as a float64.
+func parseNumber(s string) interface{} {
+	if !strings.ContainsAny(s, ".eE") {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v
+		}
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
 type jsonRow struct {
 	values []interface{}
 	result *jsonResult
@@ -372,9 +416,8 @@ func (r jsonRow) Time() time.Time {
 		return time.Time{}
 	}
 
-	// Attempt to cast this to a string or float64. The time column can be
-	// either one of those two values. It will either be the number of
-	// nanoseconds since the epoch or a string in RFC3339Nano format.
+	// Attempt to cast this to a string or a number. It will either be the
+	// number of nanoseconds since the epoch or a string in RFC3339Nano format.
 	switch v := v.(type) {
 	case string:
 		// Parse the time using RFC3339Nano. This also accepts RFC3339 without
@@ -382,6 +425,10 @@ func (r jsonRow) Time() time.Time {
 		// a time value.
 		t, _ := time.Parse(time.RFC3339Nano, v)
 		return t
+	case time.Time:
+		return v
+	case int64:
+		return time.Unix(0, v).UTC()
 	case float64:
 		return time.Unix(0, int64(v)).UTC()
 	}
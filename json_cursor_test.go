@@ -1,6 +1,7 @@
 package influxdb_test
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -48,13 +49,13 @@ func TestCursor_JSON_Basic(t *testing.T) {
 
 	if got, err := series.NextRow(); err != nil {
 		t.Fatalf("unexpected err: %v", err)
-	} else if want := []interface{}{"2010-01-01T00:00:00Z", float64(2)}; !reflect.DeepEqual(got.Values(), want) {
+	} else if want := []interface{}{"2010-01-01T00:00:00Z", int64(2)}; !reflect.DeepEqual(got.Values(), want) {
 		t.Fatalf("got %#v; want %#v", got.Values(), want)
 	}
 
 	if got, err := series.NextRow(); err != nil {
 		t.Fatalf("unexpected err: %v", err)
-	} else if want := []interface{}{"2010-01-01T00:00:10Z", float64(3)}; !reflect.DeepEqual(got.Values(), want) {
+	} else if want := []interface{}{"2010-01-01T00:00:10Z", int64(3)}; !reflect.DeepEqual(got.Values(), want) {
 		t.Fatalf("got %#v; want %#v", got.Values(), want)
 	}
 
@@ -99,7 +100,7 @@ func TestCursor_JSON_ResultError(t *testing.T) {
 
 	if got, err := series.NextRow(); err != nil {
 		t.Fatalf("unexpected err: %v", err)
-	} else if want := []interface{}{"2010-01-01T00:00:00Z", float64(2)}; !reflect.DeepEqual(got.Values(), want) {
+	} else if want := []interface{}{"2010-01-01T00:00:00Z", int64(2)}; !reflect.DeepEqual(got.Values(), want) {
 		t.Fatalf("got %#v; want %#v", got.Values(), want)
 	}
 
@@ -164,15 +165,54 @@ func TestCursor_JSON_Row(t *testing.T) {
 		t.Fatalf("got %#v; want %#v", got, want)
 	}
 
-	if got, want := row.ValueByName("value"), float64(2); got != want {
+	if got, want := row.ValueByName("value"), int64(2); got != want {
 		t.Fatalf("got %#v; want %#v", got, want)
 	}
 
-	if got, want := row.Value(1), float64(2); got != want {
+	if got, want := row.Value(1), int64(2); got != want {
 		t.Fatalf("got %#v; want %#v", got, want)
 	}
 }
 
+func TestCursor_JSON_Numbers(t *testing.T) {
+	r := strings.NewReader(`{"results":[{"series":[{"name":"cpu","columns":["time","count","value","big"],"values":[[1262304000000000001,9007199254740993,2.5,18446744073709551615],[1262304000000000002,-3,1e3,null]]}]}]}`)
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(r), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []interface{}{int64(1262304000000000001), int64(9007199254740993), float64(2.5), float64(18446744073709551615)}; !reflect.DeepEqual(row.Values(), want) {
+		t.Fatalf("got %#v; want %#v", row.Values(), want)
+	}
+	if got, want := row.Time(), time.Unix(0, 1262304000000000001).UTC(); got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+
+	row, err = series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []interface{}{int64(1262304000000000002), int64(-3), float64(1000), nil}; !reflect.DeepEqual(row.Values(), want) {
+		t.Fatalf("got %#v; want %#v", row.Values(), want)
+	}
+}
+
 func mustParseTime(s string) time.Time {
 	t, err := time.Parse(time.RFC3339Nano, s)
 	if err != nil {
@@ -180,3 +220,44 @@ func mustParseTime(s string) time.Time {
 	}
 	return t
 }
+
+func TestCursor_JSON_ContextCancel(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, `{"results":[{"series":[{"name":"cpu","columns":["time","value"],"values":[["2010-01-01T00:00:00Z",2]],"partial":true}],"partial":true}]}`)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cur, err := influxdb.NewCursorContext(ctx, pr, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := series.NextRow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The pipe has no more data so the decoder blocks until cancelled.
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := series.NextRow(); err != context.Canceled {
+		t.Fatalf("got error %v; want %v", err, context.Canceled)
+	}
+
+	// The reader should have been closed by the cancellation.
+	if _, err := pw.Write([]byte("{}")); err != io.ErrClosedPipe {
+		t.Fatalf("got error %v; want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,186 @@
+package influxdb
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// MarshalPoint converts a struct into a Point. The struct fields are mapped
+// using the influx struct tag:
+//
+//	Measurement string    `influx:"measurement"`
+//	Host        string    `influx:"tag,host"`
+//	Region      string    `influx:"tag,region,omitempty"`
+//	Value       float64   `influx:"field,value"`
+//	Time        time.Time `influx:"time"`
+//
+// The first element of the tag is the kind of value and the second element is
+// the name of the tag or field. If the name is omitted, the name of the struct
+// field is used. Tags and fields marked with omitempty are skipped when they
+// hold the zero value and nil pointers are always skipped. Embedded structs
+// without a tag have their fields promoted.
+//
+// Tag values may be strings, numbers, booleans, or any type implementing
+// encoding.TextMarshaler or fmt.Stringer. The tags of the returned Point are
+// sorted by key.
+func MarshalPoint(v interface{}) (Point, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return Point{}, fmt.Errorf("cannot marshal a nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return Point{}, fmt.Errorf("cannot marshal %T into a point", v)
+	}
+
+	var pt Point
+	info := getStructInfo(rv.Type())
+	for _, f := range info.fields {
+		fv, ok := fieldByIndexNoAlloc(rv, f.index)
+		if !ok {
+			continue
+		}
+
+		// Dereference pointers and skip nil values.
+		fv = indirect(fv)
+		if !fv.IsValid() || (f.omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		switch f.kind {
+		case fieldKindMeasurement:
+			name, err := marshalTagValue(fv)
+			if err != nil {
+				return Point{}, fmt.Errorf("measurement %s: %s", f.goName, err)
+			}
+			pt.Name = name
+		case fieldKindTag:
+			value, err := marshalTagValue(fv)
+			if err != nil {
+				return Point{}, fmt.Errorf("tag %s: %s", f.name, err)
+			}
+			pt.Tags = append(pt.Tags, Tag{Key: f.name, Value: value})
+		case fieldKindField:
+			value, err := marshalFieldValue(fv)
+			if err != nil {
+				return Point{}, fmt.Errorf("field %s: %s", f.name, err)
+			}
+			if pt.Fields == nil {
+				pt.Fields = make(map[string]interface{}, len(info.fields))
+			}
+			pt.Fields[f.name] = value
+		case fieldKindTime:
+			t, ok := fv.Interface().(time.Time)
+			if !ok {
+				return Point{}, fmt.Errorf("time %s: invalid type %s", f.goName, fv.Type())
+			}
+			pt.Time = t
+		}
+	}
+
+	if pt.Name == "" {
+		return Point{}, ErrNoMeasurement
+	} else if len(pt.Fields) == 0 {
+		return Point{}, ErrNoFields
+	}
+	sort.Sort(pt.Tags)
+	return pt, nil
+}
+
+// fieldByIndexNoAlloc returns the nested field. If an embedded pointer is nil,
+// this returns false.
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// indirect dereferences any pointers or interfaces. If a nil value is found,
+// this returns the zero Value.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// marshalTagValue formats a value as a tag value.
+func marshalTagValue(v reflect.Value) (string, error) {
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	} else if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	} else if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("invalid tag type: %s", v.Type())
+}
+
+// marshalFieldValue converts a value into one of the field types supported by
+// the line protocol.
+func marshalFieldValue(v reflect.Value) (interface{}, error) {
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32:
+		return float32(v.Float()), nil
+	case reflect.Float64:
+		return v.Float(), nil
+	}
+	return nil, fmt.Errorf("invalid field type: %s", v.Type())
+}
@@ -0,0 +1,143 @@
+package influxdb_test
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+type hostTags struct {
+	Host string `influx:"tag,host"`
+	IP   net.IP `influx:"tag,ip,omitempty"`
+}
+
+type diskStats struct {
+	*hostTags
+	Name  string    `influx:"measurement"`
+	Path  string    `influx:"tag,path,omitempty"`
+	Used  uint32    `influx:"field,used"`
+	Free  *float64  `influx:"field,free"`
+	Ratio float32   `influx:"field,ratio,omitempty"`
+	Time  time.Time `influx:"time"`
+	Note  string
+}
+
+func TestMarshalPoint(t *testing.T) {
+	free := 0.25
+	ts := time.Unix(10, 0)
+	got, err := influxdb.MarshalPoint(&diskStats{
+		hostTags: &hostTags{Host: "server01", IP: net.IPv4(10, 0, 0, 1)},
+		Name:     "disk",
+		Used:     42,
+		Free:     &free,
+		Time:     ts,
+		Note:     "ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := influxdb.Point{
+		Name: "disk",
+		Tags: influxdb.Tags{
+			{Key: "host", Value: "server01"},
+			{Key: "ip", Value: "10.0.0.1"},
+		},
+		Fields: map[string]interface{}{
+			"used": uint64(42),
+			"free": 0.25,
+		},
+		Time: ts,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
+
+// hostname is an unexported fmt.Stringer so it cannot be read when it is
+// embedded.
+type hostname string
+
+func (h hostname) String() string { return string(h) }
+
+func TestMarshalPoint_UnexportedEmbeddedField(t *testing.T) {
+	got, err := influxdb.MarshalPoint(struct {
+		hostname `influx:"tag,host"`
+		Name     string `influx:"measurement"`
+		Value    int    `influx:"field,value"`
+	}{hostname: "server01", Name: "cpu", Value: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": int64(5)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
+
+func TestMarshalPoint_Errors(t *testing.T) {
+	if _, err := influxdb.MarshalPoint(diskStats{Used: 1}); err != influxdb.ErrNoMeasurement {
+		t.Errorf("got error %v; want %v", err, influxdb.ErrNoMeasurement)
+	}
+
+	var noFields struct {
+		Name string `influx:"measurement"`
+		Free *int   `influx:"field,free"`
+	}
+	noFields.Name = "disk"
+	if _, err := influxdb.MarshalPoint(noFields); err != influxdb.ErrNoFields {
+		t.Errorf("got error %v; want %v", err, influxdb.ErrNoFields)
+	}
+
+	var invalid struct {
+		Name  string         `influx:"measurement"`
+		Value map[string]int `influx:"field,value"`
+	}
+	invalid.Name = "disk"
+	invalid.Value = map[string]int{}
+	if _, err := influxdb.MarshalPoint(invalid); err == nil {
+		t.Error("expected error for an invalid field type")
+	}
+
+	if _, err := influxdb.MarshalPoint(5); err == nil {
+		t.Error("expected error for a non-struct value")
+	}
+}
+
+func TestWriter_WriteStructs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		if got, want := string(data), "disk,host=server01 used=1i 10\ndisk,host=server02,path=/var used=2i 20\n"; got != want {
+			t.Errorf("body = %q; want %q", got, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.Database = "db0"
+	writer.Precision = influxdb.PrecisionSecond
+
+	stats := []diskStats{
+		{hostTags: &hostTags{Host: "server01"}, Name: "disk", Used: 1, Time: time.Unix(10, 0)},
+		{hostTags: &hostTags{Host: "server02"}, Name: "disk", Path: "/var", Used: 2, Time: time.Unix(20, 0)},
+	}
+	if _, err := writer.WriteStructs(stats); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,546 @@
+package influxdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// msgpackTimeExtension is the extension type the server uses for time values.
+const msgpackTimeExtension = 5
+
+// msgpackMaxPrealloc is the largest number of elements or bytes that are
+// allocated up front for a length read from the stream. Larger values grow as
+// they are read so a corrupt length cannot allocate more memory than the
+// stream contains.
+const msgpackMaxPrealloc = 4096
+
+// newMsgpackCursor creates a cursor for the msgpack output. The msgpack output
+// has the same structure as the JSON output so it reuses the JSON cursor with
+// a msgpack decoder.
+func newMsgpackCursor(r io.ReadCloser) *jsonCursor {
+	return &jsonCursor{
+		r:   r,
+		dec: &msgpackDecoder{r: bufio.NewReader(r)},
+	}
+}
+
+// msgpackDecoder decodes responses from a msgpack stream. It only supports
+// decoding into a *jsonResponse.
+type msgpackDecoder struct {
+	r *bufio.Reader
+}
+
+func (d *msgpackDecoder) Decode(v interface{}) error {
+	resp, ok := v.(*jsonResponse)
+	if !ok {
+		return fmt.Errorf("msgpack: cannot decode into %T", v)
+	}
+
+	// Check for the end of the stream before reading the response so a
+	// clean end of the stream is reported as io.EOF.
+	if _, err := d.r.Peek(1); err != nil {
+		return err
+	}
+
+	n, err := d.readMapLen()
+	if err != nil {
+		return err
+	}
+
+	resp.Results = nil
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "results":
+			if resp.Results, err = d.readResults(); err != nil {
+				return err
+			}
+		case "error":
+			msg, err := d.readString()
+			if err != nil {
+				return err
+			}
+			return ErrResult{Err: msg}
+		default:
+			if _, err := d.readValue(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) readResults() ([]*jsonResult, error) {
+	n, err := d.readArrayLen()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*jsonResult, 0, preallocLen(n))
+	for i := 0; i < n; i++ {
+		result := &jsonResult{}
+		if err := d.readResult(result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (d *msgpackDecoder) readResult(result *jsonResult) error {
+	n, err := d.readMapLen()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "series":
+			var sz int
+			if sz, err = d.readArrayLen(); err != nil {
+				return err
+			}
+			result.Series = make([]jsonRawSeries, 0, preallocLen(sz))
+			for j := 0; j < sz; j++ {
+				var series jsonRawSeries
+				if err := d.readSeries(&series); err != nil {
+					return err
+				}
+				result.Series = append(result.Series, series)
+			}
+		case "messages":
+			var sz int
+			if sz, err = d.readArrayLen(); err != nil {
+				return err
+			}
+			result.MessageList = make([]*Message, 0, preallocLen(sz))
+			for j := 0; j < sz; j++ {
+				m := &Message{}
+				if err := d.readMessage(m); err != nil {
+					return err
+				}
+				result.MessageList = append(result.MessageList, m)
+			}
+		case "partial":
+			result.Partial, err = d.readBool()
+		case "error":
+			result.Err, err = d.readString()
+		default:
+			_, err = d.readValue()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) readSeries(series *jsonRawSeries) error {
+	n, err := d.readMapLen()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "name":
+			series.Name, err = d.readString()
+		case "tags":
+			var sz int
+			if sz, err = d.readMapLen(); err != nil {
+				return err
+			}
+			series.Tags = make(map[string]string, preallocLen(sz))
+			for j := 0; j < sz; j++ {
+				k, err := d.readString()
+				if err != nil {
+					return err
+				}
+				v, err := d.readString()
+				if err != nil {
+					return err
+				}
+				series.Tags[k] = v
+			}
+		case "columns":
+			var sz int
+			if sz, err = d.readArrayLen(); err != nil {
+				return err
+			}
+			series.Columns = make([]string, 0, preallocLen(sz))
+			for j := 0; j < sz; j++ {
+				var column string
+				if column, err = d.readString(); err != nil {
+					return err
+				}
+				series.Columns = append(series.Columns, column)
+			}
+		case "values":
+			var sz int
+			if sz, err = d.readArrayLen(); err != nil {
+				return err
+			}
+			series.Values = make([][]interface{}, 0, preallocLen(sz))
+			for j := 0; j < sz; j++ {
+				var row int
+				if row, err = d.readArrayLen(); err != nil {
+					return err
+				}
+				var values []interface{}
+				if values, err = d.readArray(row); err != nil {
+					return err
+				}
+				series.Values = append(series.Values, values)
+			}
+		case "partial":
+			series.Partial, err = d.readBool()
+		default:
+			_, err = d.readValue()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *msgpackDecoder) readMessage(m *Message) error {
+	n, err := d.readMapLen()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "level":
+			m.Level, err = d.readString()
+		case "text":
+			m.Text, err = d.readString()
+		default:
+			_, err = d.readValue()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMapLen reads the header for a map and returns the number of entries.
+// A nil value is treated as an empty map.
+func (d *msgpackDecoder) readMapLen() (int, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case c >= 0x80 && c <= 0x8f:
+		return int(c & 0x0f), nil
+	case c == 0xde:
+		n, err := d.readUint(2)
+		return int(n), err
+	case c == 0xdf:
+		n, err := d.readUint(4)
+		return int(n), err
+	case c == 0xc0:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("msgpack: expected map, got format 0x%02x", c)
+}
+
+// readArrayLen reads the header for an array and returns the number of
+// elements. A nil value is treated as an empty array.
+func (d *msgpackDecoder) readArrayLen() (int, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case c >= 0x90 && c <= 0x9f:
+		return int(c & 0x0f), nil
+	case c == 0xdc:
+		n, err := d.readUint(2)
+		return int(n), err
+	case c == 0xdd:
+		n, err := d.readUint(4)
+		return int(n), err
+	case c == 0xc0:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("msgpack: expected array, got format 0x%02x", c)
+}
+
+func (d *msgpackDecoder) readString() (string, error) {
+	v, err := d.readValue()
+	if err != nil {
+		return "", err
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("msgpack: expected string, got %T", v)
+}
+
+func (d *msgpackDecoder) readBool() (bool, error) {
+	v, err := d.readValue()
+	if err != nil {
+		return false, err
+	}
+
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("msgpack: expected bool, got %T", v)
+}
+
+// readValue reads any value from the stream. Integers are returned as an
+// int64 unless they are too large, in which case they are returned as a
+// uint64. Floats are returned as a float64 and time extensions are returned
+// as a time.Time.
+func (d *msgpackDecoder) readValue() (interface{}, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0x80 && c <= 0x8f:
+		return d.readMap(int(c & 0x0f))
+	case c >= 0x90 && c <= 0x9f:
+		return d.readArray(int(c & 0x0f))
+	case c >= 0xa0 && c <= 0xbf:
+		b, err := d.readBytes(int(c & 0x1f))
+		return string(b), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.readBytes(int(n))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.readExt(int(n))
+	case 0xca:
+		n, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.readUint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.readUint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		} else if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		sz := 1 << (c - 0xd0)
+		n, err := d.readUint(sz)
+		if err != nil {
+			return nil, err
+		}
+		// Sign extend the value to 64 bits.
+		shift := uint(64 - 8*sz)
+		return int64(n<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.readExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.readBytes(int(n))
+		return string(b), err
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.readArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.readMap(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: invalid format 0x%02x", c)
+}
+
+func (d *msgpackDecoder) readArray(n int) ([]interface{}, error) {
+	values := make([]interface{}, 0, preallocLen(n))
+	for i := 0; i < n; i++ {
+		v, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (d *msgpackDecoder) readMap(n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, preallocLen(n))
+	for i := 0; i < n; i++ {
+		k, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// readExt reads an extension with a data length of n. Time extensions are
+// decoded into a time.Time and any other extension is returned as raw bytes.
+func (d *msgpackDecoder) readExt(n int) (interface{}, error) {
+	typ, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := d.readBytes(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch int8(typ) {
+	case msgpackTimeExtension:
+		// The time is encoded as the seconds since the epoch as an int64
+		// followed by the nanoseconds as an int32.
+		if len(data) == 12 {
+			sec := int64(binary.BigEndian.Uint64(data[:8]))
+			nsec := int64(int32(binary.BigEndian.Uint32(data[8:])))
+			return time.Unix(sec, nsec).UTC(), nil
+		}
+	case -1:
+		// This is the timestamp extension from the msgpack specification.
+		switch len(data) {
+		case 4:
+			return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+		case 8:
+			v := binary.BigEndian.Uint64(data)
+			return time.Unix(int64(v&0x3ffffffff), int64(v>>34)).UTC(), nil
+		case 12:
+			nsec := int64(binary.BigEndian.Uint32(data[:4]))
+			sec := int64(binary.BigEndian.Uint64(data[4:]))
+			return time.Unix(sec, nsec).UTC(), nil
+		}
+	}
+	return data, nil
+}
+
+func (d *msgpackDecoder) readByte() (byte, error) {
+	c, err := d.r.ReadByte()
+	return c, unexpectedEOF(err)
+}
+
+// readUint reads a big endian unsigned integer that is sz bytes long.
+func (d *msgpackDecoder) readUint(sz int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[:sz]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+
+	var n uint64
+	for _, b := range buf[:sz] {
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+// readBytes reads n bytes from the stream. The buffer grows as the bytes are
+// read instead of being allocated from the length up front.
+func (d *msgpackDecoder) readBytes(n int) ([]byte, error) {
+	b := make([]byte, 0, preallocLen(n))
+	for len(b) < n {
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)]
+		}
+		end := cap(b)
+		if end > n {
+			end = n
+		}
+		read, err := io.ReadFull(d.r, b[len(b):end])
+		b = b[:len(b)+read]
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+	return b, nil
+}
+
+// preallocLen limits a length read from the stream to msgpackMaxPrealloc.
+func preallocLen(n int) int {
+	if n > msgpackMaxPrealloc {
+		return msgpackMaxPrealloc
+	}
+	return n
+}
+
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF. This is used when
+// the stream ends in the middle of a value.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,282 @@
+package influxdb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+// mpMap is a msgpack map that keeps the keys in order.
+type mpMap []mpEntry
+
+type mpEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// encodeMsgpack encodes the value in the same format the server uses.
+func encodeMsgpack(buf *bytes.Buffer, v interface{}) {
+	writeLen := func(fix, c16, c32 byte, n int) {
+		switch {
+		case n < 16:
+			buf.WriteByte(fix | byte(n))
+		case n <= math.MaxUint16:
+			buf.WriteByte(c16)
+			binary.Write(buf, binary.BigEndian, uint16(n))
+		default:
+			buf.WriteByte(c32)
+			binary.Write(buf, binary.BigEndian, uint32(n))
+		}
+	}
+
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case int64:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, v)
+	case uint64:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, v)
+	case float64:
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, v)
+	case string:
+		if len(v) < 32 {
+			buf.WriteByte(0xa0 | byte(len(v)))
+		} else {
+			buf.WriteByte(0xd9)
+			buf.WriteByte(byte(len(v)))
+		}
+		buf.WriteString(v)
+	case time.Time:
+		buf.Write([]byte{0xc7, 12, 5})
+		binary.Write(buf, binary.BigEndian, v.Unix())
+		binary.Write(buf, binary.BigEndian, int32(v.Nanosecond()))
+	case []interface{}:
+		writeLen(0x90, 0xdc, 0xdd, len(v))
+		for _, e := range v {
+			encodeMsgpack(buf, e)
+		}
+	case mpMap:
+		writeLen(0x80, 0xde, 0xdf, len(v))
+		for _, e := range v {
+			encodeMsgpack(buf, e.Key)
+			encodeMsgpack(buf, e.Value)
+		}
+	default:
+		panic("unsupported msgpack type")
+	}
+}
+
+func mpSeries(name string, tags mpMap, columns []interface{}, partial bool, values ...[]interface{}) mpMap {
+	m := mpMap{{"name", name}}
+	if len(tags) > 0 {
+		m = append(m, mpEntry{"tags", tags})
+	}
+	rows := make([]interface{}, len(values))
+	for i, v := range values {
+		rows[i] = v
+	}
+	m = append(m, mpEntry{"columns", columns}, mpEntry{"values", rows})
+	if partial {
+		m = append(m, mpEntry{"partial", true})
+	}
+	return m
+}
+
+func TestCursor_Msgpack_JSONParity(t *testing.T) {
+	jsonOut := `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"server01"},"columns":["time","value","status","ok"],"values":[[1262304000000000001,2.5,"running",true],[1262304010000000000,3,null,false]],"partial":true}],"partial":true}]}
+{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"server01"},"columns":["time","value","status","ok"],"values":[[1262304020000000000,4,"stopped",true]]},{"name":"cpu","tags":{"host":"server02"},"columns":["time","value","status","ok"],"values":[[1262304000000000000,-1.25,"idle",false]]}]}]}
+{"results":[{"statement_id":1,"series":[{"name":"mem","columns":["time","free"],"values":[[1262304000000000000,9007199254740993]]}]}]}
+`
+
+	cpuColumns := []interface{}{"time", "value", "status", "ok"}
+	var buf bytes.Buffer
+	encodeMsgpack(&buf, mpMap{{"results", []interface{}{
+		mpMap{
+			{"statement_id", int64(0)},
+			{"series", []interface{}{
+				mpSeries("cpu", mpMap{{"host", "server01"}}, cpuColumns, true,
+					[]interface{}{int64(1262304000000000001), 2.5, "running", true},
+					[]interface{}{int64(1262304010000000000), int64(3), nil, false},
+				),
+			}},
+			{"partial", true},
+		},
+	}}})
+	encodeMsgpack(&buf, mpMap{{"results", []interface{}{
+		mpMap{
+			{"statement_id", int64(0)},
+			{"series", []interface{}{
+				mpSeries("cpu", mpMap{{"host", "server01"}}, cpuColumns, false,
+					[]interface{}{int64(1262304020000000000), int64(4), "stopped", true},
+				),
+				mpSeries("cpu", mpMap{{"host", "server02"}}, cpuColumns, false,
+					[]interface{}{int64(1262304000000000000), -1.25, "idle", false},
+				),
+			}},
+		},
+	}}})
+	encodeMsgpack(&buf, mpMap{{"results", []interface{}{
+		mpMap{
+			{"statement_id", int64(1)},
+			{"series", []interface{}{
+				mpSeries("mem", nil, []interface{}{"time", "free"}, false,
+					[]interface{}{int64(1262304000000000000), int64(9007199254740993)},
+				),
+			}},
+		},
+	}}})
+
+	jsonCur, err := influxdb.NewCursor(ioutil.NopCloser(strings.NewReader(jsonOut)), "application/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgpackCur, err := influxdb.NewCursor(ioutil.NopCloser(&buf), "application/x-msgpack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := readAll(t, jsonCur)
+	got := readAll(t, msgpackCur)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("msgpack output does not match json output:\n\ngot=%#v\nwant=%#v\n", got, want)
+	}
+}
+
+func TestCursor_Msgpack_Time(t *testing.T) {
+	ts := time.Date(2010, 1, 1, 0, 0, 0, 5, time.UTC)
+
+	var buf bytes.Buffer
+	encodeMsgpack(&buf, mpMap{{"results", []interface{}{
+		mpMap{
+			{"statement_id", int64(0)},
+			{"series", []interface{}{
+				mpSeries("cpu", nil, []interface{}{"time", "value"}, false,
+					[]interface{}{ts, int64(2)},
+				),
+			}},
+		},
+	}}})
+
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(&buf), "msgpack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := row.Time(); !got.Equal(ts) {
+		t.Fatalf("got %v; want %v", got, ts)
+	}
+	if got, want := row.Value(1), int64(2); got != want {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
+
+func TestCursor_Msgpack_ResultError(t *testing.T) {
+	var buf bytes.Buffer
+	encodeMsgpack(&buf, mpMap{{"results", []interface{}{
+		mpMap{{"error", "expected err"}},
+	}}})
+
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(&buf), "msgpack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cur.NextSet()
+	if want := (influxdb.ErrResult{Err: "expected err"}); err != want {
+		t.Fatalf("got error %#v; want %#v", err, want)
+	}
+}
+
+func TestCursor_Msgpack_UnexpectedEOF(t *testing.T) {
+	var buf bytes.Buffer
+	encodeMsgpack(&buf, mpMap{{"results", []interface{}{
+		mpMap{
+			{"statement_id", int64(0)},
+			{"series", []interface{}{
+				mpSeries("cpu", nil, []interface{}{"time", "value"}, false,
+					[]interface{}{int64(0), int64(2)},
+				),
+			}},
+		},
+	}}})
+	buf.Truncate(buf.Len() - 3)
+
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(&buf), "msgpack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cur.NextSet(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("got error %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestCursor_Msgpack_HugeLength(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		header []byte
+	}{
+		{name: "bin32", header: []byte{0xc6, 0xff, 0xff, 0xff, 0xff}},
+		{name: "str32", header: []byte{0xdb, 0xff, 0xff, 0xff, 0xff}},
+		{name: "array32", header: []byte{0xdd, 0xff, 0xff, 0xff, 0xff}},
+		{name: "map32", header: []byte{0xdf, 0xff, 0xff, 0xff, 0xff}},
+	} {
+		// The corrupt length is the value of a second key in the response
+		// so it is decoded as a generic value. The fixmap header is bumped
+		// to make room for the extra key.
+		var buf bytes.Buffer
+		encodeMsgpack(&buf, mpMap{{"results", []interface{}{}}})
+		buf.Bytes()[0]++
+		encodeMsgpack(&buf, "unknown")
+		buf.Write(tt.header)
+
+		cur, err := influxdb.NewCursor(ioutil.NopCloser(&buf), "msgpack")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := cur.NextSet(); err != io.ErrUnexpectedEOF {
+			t.Errorf("%s: got error %v; want %v", tt.name, err, io.ErrUnexpectedEOF)
+		}
+		runtime.ReadMemStats(&after)
+
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("%s: allocated %d bytes for a truncated value", tt.name, n)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMultiWriterQueueSize is the default number of writes that can be
+// waiting for each target of a MultiWriter.
+const DefaultMultiWriterQueueSize = 100
+
+// MultiWriterOptions is a set of configuration options for configuring a
+// MultiWriter.
+type MultiWriterOptions struct {
+	// Consistency is the number of targets that must write the points before
+	// a write succeeds. ConsistencyAll waits for every target,
+	// ConsistencyQuorum waits for a majority, ConsistencyOne waits for one
+	// target, and ConsistencyAny only waits for the points to be queued for
+	// one target. If this is empty, ConsistencyAll is used.
+	Consistency Consistency
+
+	// QueueSize is the number of writes that can be waiting for each target.
+	// If the queue for a target is full, the write fails for that target
+	// with ErrQueueFull instead of waiting. If this is zero,
+	// DefaultMultiWriterQueueSize is used.
+	QueueSize int
+
+	// RetryPolicy configures how failed writes to each target are retried.
+	// Only errors matched by RetryPolicy.IsRetryable are retried. It is also
+	// used by the spools unless Spool sets its own RetryPolicy. If this is
+	// nil, failed writes are not retried.
+	RetryPolicy *RetryPolicy
+
+	// Spool configures a SpoolWriter for each target. Writes that still fail
+	// after being retried are added to the spool of the target and written in
+	// the background. A spooled write still counts as a failure for the
+	// consistency. Each target uses a numbered directory inside of Dir. If
+	// this is nil, writes that fail are discarded.
+	Spool *SpoolOptions
+
+	// ErrorHandler is called with an ErrMultiWriteTarget for each target that
+	// fails to write and does not spool the points. It is called even if the
+	// write already returned because enough other targets succeeded.
+	ErrorHandler func(err error)
+}
+
+// MultiWriter writes the same points to multiple servers concurrently. Each
+// target has its own queue so a slow target does not delay writes that do not
+// need to wait for it. It is safe to write points from multiple goroutines.
+type MultiWriter struct {
+	targets []*multiTarget
+	opt     MultiWriterOptions
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	// done is closed by Close to stop waiting between retries.
+	done chan struct{}
+}
+
+// multiTarget is a Writer that receives writes from a MultiWriter.
+type multiTarget struct {
+	w     *Writer
+	queue chan *multiWrite
+	spool *SpoolWriter
+}
+
+// multiWrite is a write that is sent to each target.
+type multiWrite struct {
+	// data is the encoded points for each target.
+	data [][]byte
+
+	// results receives the result from each target. It has room for every
+	// target so a target never waits for the caller.
+	results chan multiResult
+}
+
+// multiResult is the result of a write to a single target.
+type multiResult struct {
+	index   int
+	err     error
+	spooled bool
+}
+
+// NewMultiWriter creates a MultiWriter that writes to each of the Writers.
+// Close must be called to finish the queued writes and release the background
+// goroutines.
+func NewMultiWriter(targets []*Writer, opt MultiWriterOptions) (*MultiWriter, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("multi writer requires at least one target")
+	}
+	switch opt.Consistency {
+	case "":
+		opt.Consistency = ConsistencyAll
+	case ConsistencyAll, ConsistencyQuorum, ConsistencyOne, ConsistencyAny:
+	default:
+		return nil, fmt.Errorf("unknown consistency: %s", opt.Consistency)
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = DefaultMultiWriterQueueSize
+	}
+
+	m := &MultiWriter{
+		opt:  opt,
+		done: make(chan struct{}),
+	}
+	for i, w := range targets {
+		t := &multiTarget{
+			w:     w,
+			queue: make(chan *multiWrite, opt.QueueSize),
+		}
+		if opt.Spool != nil {
+			spoolOpt := *opt.Spool
+			spoolOpt.Dir = filepath.Join(opt.Spool.Dir, strconv.Itoa(i))
+			if spoolOpt.RetryPolicy == nil {
+				spoolOpt.RetryPolicy = opt.RetryPolicy
+			}
+			spool, err := NewSpoolWriter(w, spoolOpt)
+			if err != nil {
+				m.closeSpools()
+				return nil, err
+			}
+			t.spool = spool
+		}
+		m.targets = append(m.targets, t)
+	}
+
+	m.wg.Add(len(m.targets))
+	for i, t := range m.targets {
+		go m.run(i, t)
+	}
+	return m, nil
+}
+
+// Write writes the line protocol to every target and waits until enough
+// targets have written it to satisfy the consistency. If the consistency
+// cannot be satisfied, an ErrMultiWrite is returned.
+func (m *MultiWriter) Write(data []byte) (n int, err error) {
+	return m.WriteContext(context.Background(), data)
+}
+
+// WriteContext writes the line protocol like Write. The context only stops
+// waiting for the targets. The data is still written to the targets in the
+// background.
+func (m *MultiWriter) WriteContext(ctx context.Context, data []byte) (n int, err error) {
+	// The targets may still be writing the data after this returns so they
+	// need their own copy.
+	buf := append([]byte(nil), data...)
+	all := make([][]byte, len(m.targets))
+	for i := range all {
+		all[i] = buf
+	}
+	if err := m.write(ctx, all); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// WritePoint encodes the point and writes it to every target.
+func (m *MultiWriter) WritePoint(pt Point) error {
+	return m.WriteBatchContext(context.Background(), []Point{pt})
+}
+
+// WritePointContext encodes the point and writes it to every target.
+func (m *MultiWriter) WritePointContext(ctx context.Context, pt Point) error {
+	return m.WriteBatchContext(ctx, []Point{pt})
+}
+
+// WriteBatch encodes the points and writes them to every target.
+func (m *MultiWriter) WriteBatch(pts []Point) error {
+	return m.WriteBatchContext(context.Background(), pts)
+}
+
+// WriteBatchContext encodes the points and writes them to every target. The
+// points are encoded with the options of each target.
+func (m *MultiWriter) WriteBatchContext(ctx context.Context, pts []Point) error {
+	all := make([][]byte, len(m.targets))
+	for i, t := range m.targets {
+		data, err := t.w.encodeBatch(pts, nil)
+		if err != nil {
+			return err
+		}
+		all[i] = data
+	}
+	return m.write(ctx, all)
+}
+
+// Close waits for the queued writes to finish and stops the background
+// goroutines. Failed writes are not retried once Close is called. Any writes
+// after Close is called will return ErrWriterClosed.
+func (m *MultiWriter) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrWriterClosed
+	}
+	m.closed = true
+	close(m.done)
+	for _, t := range m.targets {
+		close(t.queue)
+	}
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	return m.closeSpools()
+}
+
+// write queues the data for each target and waits for the results.
+func (m *MultiWriter) write(ctx context.Context, data [][]byte) error {
+	mw := &multiWrite{
+		data:    data,
+		results: make(chan multiResult, len(m.targets)),
+	}
+
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrWriterClosed
+	}
+	queued := 0
+	for i, t := range m.targets {
+		select {
+		case t.queue <- mw:
+			queued++
+		default:
+			mw.results <- multiResult{index: i, err: ErrQueueFull}
+			m.handleError(ErrMultiWriteTarget{Index: i, Err: ErrQueueFull})
+		}
+	}
+	m.mu.RUnlock()
+
+	required := m.required()
+	e := ErrMultiWrite{
+		Errors:   make([]error, len(m.targets)),
+		Required: required,
+	}
+	if m.opt.Consistency == ConsistencyAny && queued > 0 {
+		return nil
+	}
+
+	failed := 0
+	for e.Written < required && failed <= len(m.targets)-required {
+		select {
+		case res := <-mw.results:
+			if res.err == nil {
+				e.Written++
+			} else {
+				e.Errors[res.index] = res.err
+				failed++
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if e.Written >= required {
+		return nil
+	}
+	return e
+}
+
+// required returns the number of targets that must write the points.
+func (m *MultiWriter) required() int {
+	switch m.opt.Consistency {
+	case ConsistencyAll:
+		return len(m.targets)
+	case ConsistencyQuorum:
+		return len(m.targets)/2 + 1
+	default:
+		return 1
+	}
+}
+
+// run is the background goroutine that writes the queued data to a target.
+func (m *MultiWriter) run(i int, t *multiTarget) {
+	defer m.wg.Done()
+
+	for mw := range t.queue {
+		res := multiResult{index: i}
+		res.err = m.writeTarget(t, mw.data[i])
+		if res.err != nil && t.spool != nil && t.spool.retryPolicy().IsRetryable(res.err) {
+			if _, err := t.spool.Write(mw.data[i]); err == nil {
+				res.spooled = true
+			}
+		}
+		mw.results <- res
+
+		// The caller may have stopped waiting for this target, so report
+		// the error here too.
+		if res.err != nil && !res.spooled {
+			m.handleError(ErrMultiWriteTarget{Index: i, Err: res.err})
+		}
+	}
+}
+
+// writeTarget writes the data to the target and retries it according to the
+// retry policy. It stops retrying once the MultiWriter is closed.
+func (m *MultiWriter) writeTarget(t *multiTarget, data []byte) error {
+	p := m.opt.RetryPolicy
+	attempts := 1
+	if p != nil {
+		attempts = p.maxAttempts()
+	}
+
+	for attempt := 1; ; attempt++ {
+		_, err := t.w.Write(data)
+		if err == nil || attempt >= attempts || !p.IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.errorBackoff(attempt, err))
+		select {
+		case <-timer.C:
+		case <-m.done:
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+func (m *MultiWriter) closeSpools() error {
+	var err error
+	for _, t := range m.targets {
+		if t.spool == nil {
+			continue
+		}
+		if cerr := t.spool.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (m *MultiWriter) handleError(err error) {
+	if m.opt.ErrorHandler != nil {
+		m.opt.ErrorHandler(err)
+	}
+}
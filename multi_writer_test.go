@@ -0,0 +1,356 @@
+package influxdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func newMultiWriter(t *testing.T, urls []string, opt influxdb.MultiWriterOptions) *influxdb.MultiWriter {
+	targets := make([]*influxdb.Writer, len(urls))
+	for i, url := range urls {
+		targets[i] = newTestWriter(t, url)
+	}
+
+	w, err := influxdb.NewMultiWriter(targets, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestMultiWriter(t *testing.T) {
+	a, b := newTestServer(t), newTestServer(t)
+	defer a.Close()
+	defer b.Close()
+
+	w := newMultiWriter(t, []string{a.URL, b.URL}, influxdb.MultiWriterOptions{})
+	defer w.Close()
+
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": 1},
+	}
+	if err := w.WritePoint(pt); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range []*testServer{a, b} {
+		if got, want := s.Lines(), []string{"cpu value=1i"}; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("%d: lines = %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestMultiWriter_Consistency(t *testing.T) {
+	for _, tt := range []struct {
+		consistency influxdb.Consistency
+		down        int
+		ok          bool
+	}{
+		{consistency: influxdb.ConsistencyAll, down: 0, ok: true},
+		{consistency: influxdb.ConsistencyAll, down: 1, ok: false},
+		{consistency: influxdb.ConsistencyQuorum, down: 1, ok: true},
+		{consistency: influxdb.ConsistencyQuorum, down: 2, ok: false},
+		{consistency: influxdb.ConsistencyOne, down: 2, ok: true},
+		{consistency: influxdb.ConsistencyOne, down: 3, ok: false},
+		{consistency: influxdb.ConsistencyAny, down: 3, ok: true},
+	} {
+		servers := []*testServer{newTestServer(t), newTestServer(t), newTestServer(t)}
+		urls := make([]string, len(servers))
+		for i, s := range servers {
+			s.SetDown(i < tt.down)
+			urls[i] = s.URL
+		}
+
+		w := newMultiWriter(t, urls, influxdb.MultiWriterOptions{Consistency: tt.consistency})
+		_, err := w.Write([]byte("cpu value=1\n"))
+		if tt.ok && err != nil {
+			t.Errorf("%s with %d down: unexpected error: %s", tt.consistency, tt.down, err)
+		} else if !tt.ok {
+			var merr influxdb.ErrMultiWrite
+			if !errors.As(err, &merr) {
+				t.Errorf("%s with %d down: err = %v; want ErrMultiWrite", tt.consistency, tt.down, err)
+			} else if !errors.Is(err, influxdb.ErrServerUnavailable) {
+				t.Errorf("%s with %d down: err = %v; want ErrServerUnavailable", tt.consistency, tt.down, err)
+			}
+		}
+		w.Close()
+
+		for _, s := range servers {
+			s.Close()
+		}
+	}
+}
+
+func TestMultiWriter_SlowTarget(t *testing.T) {
+	fast := newTestServer(t)
+	defer fast.Close()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer slow.Close()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	w := newMultiWriter(t, []string{fast.URL, slow.URL}, influxdb.MultiWriterOptions{
+		Consistency: influxdb.ConsistencyOne,
+		QueueSize:   1,
+		ErrorHandler: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+
+	// The slow target takes the first write and queues the second. The third
+	// write does not fit in its queue, but the fast target still writes it.
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("cpu value=1\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := len(fast.Lines()), 3; got != want {
+		t.Errorf("fast target lines = %d; want %d", got, want)
+	}
+
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("cpu value=1\n")); err != influxdb.ErrWriterClosed {
+		t.Errorf("err = %v; want %v", err, influxdb.ErrWriterClosed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) == 0 || !errors.Is(errs[0], influxdb.ErrQueueFull) {
+		t.Errorf("errors = %v; want %v", errs, influxdb.ErrQueueFull)
+	}
+}
+
+func TestMultiWriter_Retry(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	server.SetDown(true)
+
+	w := newMultiWriter(t, []string{server.URL}, influxdb.MultiWriterOptions{
+		RetryPolicy: &influxdb.RetryPolicy{
+			MaxAttempts: 5,
+			MinBackoff:  time.Millisecond,
+		},
+	})
+	defer w.Close()
+
+	go func() {
+		for server.Failed() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		server.SetDown(false)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := w.WriteContext(ctx, []byte("cpu value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiWriter_Spool(t *testing.T) {
+	up, down := newTestServer(t), newTestServer(t)
+	defer up.Close()
+	defer down.Close()
+	down.SetDown(true)
+
+	w := newMultiWriter(t, []string{up.URL, down.URL}, influxdb.MultiWriterOptions{
+		Consistency: influxdb.ConsistencyOne,
+		Spool: &influxdb.SpoolOptions{
+			Dir:        t.TempDir(),
+			MinBackoff: time.Millisecond,
+			MaxBackoff: 10 * time.Millisecond,
+		},
+	})
+	defer w.Close()
+
+	if _, err := w.Write([]byte("cpu value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed write is spooled and written once the server is back up.
+	// The second failure is from the spool trying to write it.
+	for down.Failed() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	down.SetDown(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(down.Lines()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("spooled write was never written")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMultiWriter_ReusedBuffer(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	w := newMultiWriter(t, []string{slow.URL}, influxdb.MultiWriterOptions{
+		Consistency: influxdb.ConsistencyAny,
+	})
+
+	// The write returns once it is queued so the caller is free to reuse the
+	// buffer while the target is still writing it.
+	buf := []byte("cpu value=1\n")
+	if _, err := w.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	copy(buf, "mem value=2\n")
+	close(release)
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := server.Lines(), []string{"cpu value=1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %q; want %q", got, want)
+	}
+}
+
+func TestMultiWriter_RetryAfter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "expected error", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := newMultiWriter(t, []string{server.URL}, influxdb.MultiWriterOptions{
+		RetryPolicy: &influxdb.RetryPolicy{
+			MaxAttempts: 2,
+			MinBackoff:  time.Millisecond,
+		},
+	})
+	defer w.Close()
+
+	if _, err := w.Write([]byte("cpu value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := len(attempts), 2; got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	} else if wait := attempts[1].Sub(attempts[0]); wait < time.Second {
+		t.Errorf("retried after %s; want at least %s", wait, time.Second)
+	}
+}
+
+func TestMultiWriter_RetryAfterLimit(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts++; attempts == 1 {
+			w.Header().Set("Retry-After", "3600")
+			http.Error(w, "expected error", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := newMultiWriter(t, []string{server.URL}, influxdb.MultiWriterOptions{
+		RetryPolicy: &influxdb.RetryPolicy{
+			MaxAttempts: 2,
+			MinBackoff:  time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+		},
+	})
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := w.WriteContext(ctx, []byte("cpu value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiWriter_CloseDuringBackoff(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	server.SetDown(true)
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	w := newMultiWriter(t, []string{server.URL}, influxdb.MultiWriterOptions{
+		Consistency: influxdb.ConsistencyAny,
+		RetryPolicy: &influxdb.RetryPolicy{
+			MinBackoff: time.Hour,
+			MaxBackoff: time.Hour,
+		},
+		ErrorHandler: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+
+	if _, err := w.Write([]byte("cpu value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+	for server.Failed() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Close stops waiting for the backoff and reports the last error.
+	closed := make(chan error, 1)
+	go func() { closed <- w.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not interrupt the backoff")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], influxdb.ErrServerUnavailable) {
+		t.Errorf("errors = %v; want %v", errs, influxdb.ErrServerUnavailable)
+	}
+}
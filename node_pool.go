@@ -0,0 +1,435 @@
+package influxdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultNodeMaxFailures is the default number of consecutive failures
+	// before a node is ejected from a NodePool.
+	DefaultNodeMaxFailures = 3
+
+	// DefaultNodeEjectDuration is the default amount of time an ejected node
+	// is skipped before requests are sent to it again.
+	DefaultNodeEjectDuration = 30 * time.Second
+)
+
+// NodeStrategy decides which node in a NodePool receives a request.
+type NodeStrategy string
+
+const (
+	// NodeRoundRobin sends each request to the next healthy node in turn.
+	NodeRoundRobin = NodeStrategy("round-robin")
+
+	// NodeLeastOutstanding sends each request to the healthy node with the
+	// fewest requests in progress.
+	NodeLeastOutstanding = NodeStrategy("least-outstanding")
+
+	// NodePrimaryFailover sends every request to the first healthy node in
+	// the order the nodes were given.
+	NodePrimaryFailover = NodeStrategy("primary-failover")
+)
+
+func (s NodeStrategy) String() string {
+	return string(s)
+}
+
+// NodePoolOptions is a set of configuration options for configuring a NodePool.
+type NodePoolOptions struct {
+	// Strategy decides which node receives each request. If this is empty,
+	// NodeRoundRobin is used.
+	Strategy NodeStrategy
+
+	// MaxFailures is the number of consecutive failures before a node is
+	// ejected. A failure is a network error or a response that matches
+	// ErrServerUnavailable. If this is zero, DefaultNodeMaxFailures is used.
+	MaxFailures int
+
+	// EjectDuration is the amount of time an ejected node is skipped before
+	// requests are sent to it again. A successful health check returns the
+	// node to the pool early if it was ejected by failed health checks. A
+	// node ejected by failed requests stays ejected until a request to it
+	// succeeds. If this is zero, DefaultNodeEjectDuration is used.
+	EjectDuration time.Duration
+
+	// HealthCheckInterval is the interval between pings of every node. A
+	// failed ping counts as a failure of the node. If this is zero, the
+	// nodes are not pinged and failures are only detected from requests.
+	HealthCheckInterval time.Duration
+
+	// Client is the HTTP client used to ping the nodes. If this is nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// Auth holds the credentials sent with the pings. Credentials in the URL
+	// of a node are used for that node instead.
+	Auth *Auth
+}
+
+// NodeStatus is the current state of a node in a NodePool.
+type NodeStatus struct {
+	// URL is the base URL of the node.
+	URL string
+
+	// Healthy is false while the node is ejected from the pool.
+	Healthy bool
+
+	// Outstanding is the number of requests in progress.
+	Outstanding int
+
+	// Failures is the number of consecutive failures.
+	Failures int
+}
+
+// NodePool spreads the requests from a Client across multiple servers and
+// stops sending requests to servers that are failing. Set it as the Nodes of a
+// Client to use it with the Querier and Writer of that Client. Requests are
+// sent with the HTTP client and credentials of the Client that sends them,
+// unless the URL of the node contains credentials.
+//
+// If a request to a node fails with a network error and the request can be
+// sent again, it is sent to another node. Retrying requests that receive an
+// error response is handled by the RetryPolicy of the Client, which picks a
+// node again for each attempt.
+type NodePool struct {
+	opt NodePoolOptions
+
+	mu    sync.Mutex
+	nodes []*node
+	next  int
+
+	done    chan struct{}
+	stopped chan struct{}
+	closed  bool
+}
+
+// node is a server in a NodePool.
+type node struct {
+	// c is used to build the URLs for the node and holds the credentials
+	// from its URL.
+	c   *Client
+	url string
+
+	outstanding int
+	failures    int
+	ejected     time.Time
+
+	// requestFailed is set while the failures include a failed request. A
+	// successful health check does not clear these failures since the node
+	// may answer pings while failing requests.
+	requestFailed bool
+}
+
+// NewNodePool creates a NodePool for the servers at the URLs. Close must be
+// called to stop the health checks.
+func NewNodePool(urls []string, opt NodePoolOptions) (*NodePool, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("node pool requires at least one url")
+	}
+	switch opt.Strategy {
+	case "":
+		opt.Strategy = NodeRoundRobin
+	case NodeRoundRobin, NodeLeastOutstanding, NodePrimaryFailover:
+	default:
+		return nil, fmt.Errorf("unknown node strategy: %s", opt.Strategy)
+	}
+	if opt.MaxFailures <= 0 {
+		opt.MaxFailures = DefaultNodeMaxFailures
+	}
+	if opt.EjectDuration <= 0 {
+		opt.EjectDuration = DefaultNodeEjectDuration
+	}
+	if opt.Client == nil {
+		opt.Client = http.DefaultClient
+	}
+
+	p := &NodePool{
+		opt:     opt,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for _, rawurl := range urls {
+		c, err := NewClient(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		p.nodes = append(p.nodes, &node{c: c, url: rawurl})
+	}
+
+	if opt.HealthCheckInterval > 0 {
+		go p.run()
+	} else {
+		close(p.stopped)
+	}
+	return p, nil
+}
+
+// Status returns the current state of each node in the order the nodes were
+// given.
+func (p *NodePool) Status() []NodeStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := make([]NodeStatus, len(p.nodes))
+	for i, n := range p.nodes {
+		status[i] = NodeStatus{
+			URL:         n.url,
+			Healthy:     n.healthy(now),
+			Outstanding: n.outstanding,
+			Failures:    n.failures,
+		}
+	}
+	return status
+}
+
+// Close stops the health checks.
+func (p *NodePool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.done)
+	<-p.stopped
+	return nil
+}
+
+// do sends the request to one of the nodes. The path of the request is moved
+// from the path prefix of the client to the path prefix of the node.
+func (p *NodePool) do(c *Client, req *http.Request, idempotent bool) (*http.Response, error) {
+	ctx := req.Context()
+	path := strings.TrimPrefix(req.URL.Path, strings.TrimRight(c.Path, "/"))
+
+	var tried []*node
+	for {
+		n := p.pick(tried)
+
+		r := req.Clone(ctx)
+		if len(tried) > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		u := n.c.url(path)
+		u.RawQuery = req.URL.RawQuery
+		r.URL, r.Host = &u, u.Host
+		n.c.Auth.setAuth(r)
+
+		resp, err := c.Client.Do(r)
+		p.report(n, resp, err)
+		if err == nil {
+			// The request is outstanding until the response has been read.
+			resp.Body = &nodeBody{ReadCloser: resp.Body, p: p, n: n}
+		} else {
+			p.release(n)
+		}
+		tried = append(tried, n)
+
+		if err == nil || !idempotent || ctx.Err() != nil || len(tried) == len(p.nodes) {
+			return resp, err
+		} else if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+	}
+}
+
+// pick chooses a node that has not been tried for the request yet and marks
+// a request as outstanding on it. Ejected nodes are only chosen if every
+// remaining node is ejected.
+func (p *NodePool) pick(tried []*node) *node {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var candidates []*node
+	for _, healthy := range []bool{true, false} {
+		for i := range p.nodes {
+			// Start at the next node for round robin so the order of
+			// the candidates is the order they should be used in.
+			n := p.nodes[i]
+			if p.opt.Strategy == NodeRoundRobin {
+				n = p.nodes[(p.next+i)%len(p.nodes)]
+			}
+			if n.healthy(now) == healthy && !containsNode(tried, n) {
+				candidates = append(candidates, n)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+
+	n := candidates[0]
+	switch p.opt.Strategy {
+	case NodeRoundRobin:
+		for i := range p.nodes {
+			if p.nodes[i] == n {
+				p.next = (i + 1) % len(p.nodes)
+			}
+		}
+	case NodeLeastOutstanding:
+		for _, c := range candidates[1:] {
+			if c.outstanding < n.outstanding {
+				n = c
+			}
+		}
+	}
+	n.outstanding++
+	return n
+}
+
+// report records the result of a request sent to the node.
+func (p *NodePool) report(n *node, resp *http.Response, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	p.record(n, err == nil && !(ErrHTTP{StatusCode: resp.StatusCode}).Is(ErrServerUnavailable))
+}
+
+// release marks a request to the node as finished.
+func (p *NodePool) release(n *node) {
+	p.mu.Lock()
+	n.outstanding--
+	p.mu.Unlock()
+}
+
+// record updates the failures of the node with the result of a request and
+// ejects it if it has failed too many times. The lock must be held.
+func (p *NodePool) record(n *node, ok bool) {
+	if ok {
+		n.failures = 0
+		n.ejected = time.Time{}
+		n.requestFailed = false
+		return
+	}
+	n.requestFailed = true
+	p.fail(n)
+}
+
+// recordCheck updates the failures of the node with the result of a health
+// check. A successful check only returns the node to the pool if no request
+// to it has failed since its last successful request. The lock must be held.
+func (p *NodePool) recordCheck(n *node, ok bool) {
+	if !ok {
+		p.fail(n)
+	} else if !n.requestFailed {
+		n.failures = 0
+		n.ejected = time.Time{}
+	}
+}
+
+// fail counts a failure of the node and ejects it if it has failed too many
+// times. The lock must be held.
+func (p *NodePool) fail(n *node) {
+	n.failures++
+	if n.failures >= p.opt.MaxFailures {
+		n.ejected = time.Now().Add(p.opt.EjectDuration)
+	}
+}
+
+// run is the background goroutine that pings the nodes.
+func (p *NodePool) run() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.opt.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.check()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// check pings every node at the same time and records the results.
+func (p *NodePool) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opt.HealthCheckInterval)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, n := range p.nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			err := p.ping(ctx, n)
+
+			p.mu.Lock()
+			p.recordCheck(n, err == nil)
+			p.mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+}
+
+// ping sends a ping to the node with the HTTP client and credentials from the
+// options.
+func (p *NodePool) ping(ctx context.Context, n *node) error {
+	u := n.c.url("/ping")
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if n.c.Auth != nil {
+		n.c.Auth.setAuth(req)
+	} else {
+		p.opt.Auth.setAuth(req)
+	}
+
+	resp, err := p.opt.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// nodeBody releases the request on the node when the response body is closed.
+type nodeBody struct {
+	io.ReadCloser
+	p    *NodePool
+	n    *node
+	once sync.Once
+}
+
+func (b *nodeBody) Close() error {
+	b.once.Do(func() { b.p.release(b.n) })
+	return b.ReadCloser.Close()
+}
+
+// healthy returns false if the node is ejected at the given time.
+func (n *node) healthy(now time.Time) bool {
+	return n.ejected.IsZero() || now.After(n.ejected)
+}
+
+func containsNode(nodes []*node, n *node) bool {
+	for _, other := range nodes {
+		if other == n {
+			return true
+		}
+	}
+	return false
+}
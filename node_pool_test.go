@@ -0,0 +1,327 @@
+package influxdb_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func newNodeClient(t *testing.T, urls []string, opt influxdb.NodePoolOptions) *influxdb.Client {
+	pool, err := influxdb.NewNodePool(urls, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	return &influxdb.Client{Nodes: pool}
+}
+
+func TestNodePool_RoundRobin(t *testing.T) {
+	servers := []*testServer{newTestServer(t), newTestServer(t), newTestServer(t)}
+	urls := make([]string, len(servers))
+	for i, s := range servers {
+		defer s.Close()
+		urls[i] = s.URL
+	}
+
+	client := newNodeClient(t, urls, influxdb.NodePoolOptions{})
+	writer := client.Writer()
+	for i := 0; i < 6; i++ {
+		if _, err := writer.Write([]byte("cpu value=1\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, s := range servers {
+		if got, want := s.Requests(), 2; got != want {
+			t.Errorf("%d: requests = %d; want %d", i, got, want)
+		}
+	}
+}
+
+func TestNodePool_PrimaryFailover(t *testing.T) {
+	primary, secondary := newTestServer(t), newTestServer(t)
+	defer secondary.Close()
+
+	// Close the primary so requests to it fail with a network error.
+	primaryURL := primary.URL
+	primary.Close()
+
+	client := newNodeClient(t, []string{primaryURL, secondary.URL}, influxdb.NodePoolOptions{
+		Strategy:    influxdb.NodePrimaryFailover,
+		MaxFailures: 2,
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := client.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := secondary.Requests(), 3; got != want {
+		t.Errorf("secondary requests = %d; want %d", got, want)
+	}
+
+	status := client.Nodes.Status()
+	if got, want := status[0].Healthy, false; got != want {
+		t.Errorf("primary healthy = %v; want %v", got, want)
+	}
+	if got, want := status[0].Failures, 2; got != want {
+		t.Errorf("primary failures = %d; want %d", got, want)
+	}
+	if got, want := status[1].Healthy, true; got != want {
+		t.Errorf("secondary healthy = %v; want %v", got, want)
+	}
+}
+
+func TestNodePool_LeastOutstanding(t *testing.T) {
+	servers := []*testServer{newTestServer(t), newTestServer(t)}
+	urls := make([]string, len(servers))
+	for i, s := range servers {
+		defer s.Close()
+		urls[i] = s.URL
+	}
+
+	client := newNodeClient(t, urls, influxdb.NodePoolOptions{
+		Strategy: influxdb.NodeLeastOutstanding,
+	})
+	querier := client.Querier()
+
+	// The first cursor stays open so the first node has an outstanding
+	// request while the second query is sent.
+	cur, err := querier.Select("SELECT value FROM cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur2, err := querier.Select("SELECT value FROM cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := servers[1].Requests(), 1; got != want {
+		t.Errorf("second node requests = %d; want %d", got, want)
+	}
+	if got, want := client.Nodes.Status()[0].Outstanding, 1; got != want {
+		t.Errorf("outstanding = %d; want %d", got, want)
+	}
+
+	cur.Close()
+	cur2.Close()
+	for i, status := range client.Nodes.Status() {
+		if got, want := status.Outstanding, 0; got != want {
+			t.Errorf("%d: outstanding = %d; want %d", i, got, want)
+		}
+	}
+}
+
+func TestNodePool_PassiveEjection(t *testing.T) {
+	servers := []*testServer{newTestServer(t), newTestServer(t)}
+	urls := make([]string, len(servers))
+	for i, s := range servers {
+		defer s.Close()
+		urls[i] = s.URL
+	}
+	servers[0].SetDown(true)
+
+	client := newNodeClient(t, urls, influxdb.NodePoolOptions{MaxFailures: 1})
+	client.RetryPolicy = &influxdb.RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+	}
+
+	writer := client.Writer()
+	for i := 0; i < 4; i++ {
+		if _, err := writer.Write([]byte("cpu value=1\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first node fails once and is skipped for the rest of the writes.
+	if got, want := servers[0].Requests(), 1; got != want {
+		t.Errorf("first node requests = %d; want %d", got, want)
+	}
+	if got, want := servers[1].Requests(), 4; got != want {
+		t.Errorf("second node requests = %d; want %d", got, want)
+	}
+}
+
+func TestNodePool_HealthCheck(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	client := newNodeClient(t, []string{server.URL}, influxdb.NodePoolOptions{
+		MaxFailures:         1,
+		EjectDuration:       time.Hour,
+		HealthCheckInterval: time.Millisecond,
+	})
+	server.SetDown(true)
+
+	waitHealthy := func(healthy bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for client.Nodes.Status()[0].Healthy != healthy {
+			if time.Now().After(deadline) {
+				t.Fatalf("node healthy never became %v", healthy)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitHealthy(false)
+
+	server.SetDown(false)
+	waitHealthy(true)
+}
+
+// headerTransport adds a header to every request.
+type headerTransport struct {
+	key, value string
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(t.key, t.value)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNodePool_HealthCheck_Client(t *testing.T) {
+	var pings int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Token my-token"; got != want {
+			t.Errorf("Authorization = %q; want %q", got, want)
+		}
+		if got, want := r.Header.Get("X-Transport"), "client"; got != want {
+			t.Errorf("X-Transport = %q; want %q", got, want)
+		}
+		if r.URL.Path == "/ping" {
+			atomic.AddInt32(&pings, 1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The pool pings the nodes with its own HTTP client and credentials
+	// without waiting for a request.
+	newNodeClient(t, []string{server.URL}, influxdb.NodePoolOptions{
+		HealthCheckInterval: time.Millisecond,
+		Client: &http.Client{
+			Transport: headerTransport{key: "X-Transport", value: "client"},
+		},
+		Auth: &influxdb.Auth{Token: "my-token"},
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pings) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("the node was never pinged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNodePool_HealthCheck_RequestFailures(t *testing.T) {
+	var pings int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			atomic.AddInt32(&pings, 1)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.Error(w, "expected error", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newNodeClient(t, []string{server.URL}, influxdb.NodePoolOptions{
+		MaxFailures:         1,
+		EjectDuration:       time.Hour,
+		HealthCheckInterval: time.Millisecond,
+	})
+	writer := client.Writer()
+	writer.Database = "db0"
+	if _, err := writer.Write([]byte("cpu value=1\n")); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The node answers pings, but it stays ejected because requests to it
+	// are failing.
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pings) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("the node was never pinged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if client.Nodes.Status()[0].Healthy {
+		t.Fatal("node returned to the pool after failing a request")
+	}
+}
+
+func TestNodePool_URLCredentials(t *testing.T) {
+	var pings int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			t.Errorf("%s: BasicAuth = %q, %q, %v; want %q, %q, true", r.URL.Path, username, password, ok, "admin", "secret")
+		}
+		if r.URL.Path == "/ping" {
+			atomic.AddInt32(&pings, 1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.User = url.UserPassword("admin", "secret")
+
+	client := newNodeClient(t, []string{u.String()}, influxdb.NodePoolOptions{
+		HealthCheckInterval: time.Millisecond,
+		Auth:                &influxdb.Auth{Token: "pool-token"},
+	})
+	client.Auth = &influxdb.Auth{Token: "client-token"}
+
+	writer := client.Writer()
+	writer.Database = "db0"
+	if _, err := writer.Write([]byte("cpu value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pings) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("the node was never pinged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNodePool_PathPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/influxdb/write"; got != want {
+			t.Errorf("Path = %q; want %q", got, want)
+		}
+		if got, want := r.URL.Query().Get("db"), "db0"; got != want {
+			t.Errorf("db = %q; want %q", got, want)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := newNodeClient(t, []string{server.URL + "/influxdb"}, influxdb.NodePoolOptions{})
+	client.Path = "/ignored"
+
+	writer := client.Writer()
+	writer.Database = "db0"
+	if _, err := writer.Write([]byte("cpu value=1\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewNodePool_UnknownStrategy(t *testing.T) {
+	if _, err := influxdb.NewNodePool([]string{"http://localhost:8086"}, influxdb.NodePoolOptions{
+		Strategy: "random",
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+}
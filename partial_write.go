@@ -0,0 +1,164 @@
+package influxdb
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PartialWriteReason is the reason the server gave for dropping points in a
+// partial write.
+type PartialWriteReason string
+
+const (
+	// PartialWriteUnknown is used when the reason could not be determined
+	// from the server message.
+	PartialWriteUnknown = PartialWriteReason("")
+
+	// PartialWriteFieldTypeConflict is used when a field was written with a
+	// different type than the one already stored in the shard.
+	PartialWriteFieldTypeConflict = PartialWriteReason("field type conflict")
+
+	// PartialWriteRetentionPolicy is used when the points are outside of the
+	// retention policy of the database.
+	PartialWriteRetentionPolicy = PartialWriteReason("points beyond retention policy")
+
+	// PartialWriteMaxValuesPerTag is used when a point would create more
+	// values for a tag key than the server allows.
+	PartialWriteMaxValuesPerTag = PartialWriteReason("max-values-per-tag limit exceeded")
+
+	// PartialWriteUnparseable is used when the server could not parse some
+	// of the lines.
+	PartialWriteUnparseable = PartialWriteReason("unable to parse")
+)
+
+func (r PartialWriteReason) String() string {
+	return string(r)
+}
+
+var (
+	droppedRegex       = regexp.MustCompile(`\s*dropped=(\d+)\s*$`)
+	fieldConflictRegex = regexp.MustCompile(`input field "(.*?)" on measurement "(.*?)" is type (\w+), already exists as type (\w+)`)
+	maxValuesRegex     = regexp.MustCompile(`measurement="(.*?)" tag="(.*?)" value="(.*?)"`)
+	unparseableRegex   = regexp.MustCompile(`(?s)unable to parse '(.*?)': `)
+)
+
+// parsePartialWrite parses the message from the server for a partial write.
+// The data that was written is used to find the lines that were dropped.
+// The number of bytes that were written successfully is also returned.
+func parsePartialWrite(msg string, data []byte) (ErrPartialWrite, int) {
+	e := ErrPartialWrite{Err: msg}
+
+	detail := strings.TrimSpace(strings.TrimPrefix(msg, "partial write:"))
+	if m := droppedRegex.FindStringSubmatchIndex(detail); m != nil {
+		e.Dropped, _ = strconv.Atoi(detail[m[2]:m[3]])
+		detail = detail[:m[0]]
+	}
+
+	var match func(text string, pt *Point, perr error) bool
+	switch {
+	case strings.HasPrefix(detail, string(PartialWriteFieldTypeConflict)):
+		e.Reason = PartialWriteFieldTypeConflict
+		if m := fieldConflictRegex.FindStringSubmatch(detail); m != nil {
+			field, name, typ := m[1], m[2], m[3]
+			match = func(text string, pt *Point, perr error) bool {
+				if perr != nil || pt.Name != name {
+					return false
+				}
+				v, ok := pt.Fields[field]
+				return ok && fieldTypeName(v) == typ
+			}
+		}
+	case strings.HasPrefix(detail, string(PartialWriteRetentionPolicy)):
+		e.Reason = PartialWriteRetentionPolicy
+	case strings.HasPrefix(detail, string(PartialWriteMaxValuesPerTag)):
+		e.Reason = PartialWriteMaxValuesPerTag
+		if m := maxValuesRegex.FindStringSubmatch(detail); m != nil {
+			name, key, value := m[1], m[2], m[3]
+			match = func(text string, pt *Point, perr error) bool {
+				if perr != nil || pt.Name != name {
+					return false
+				}
+				v, ok := lookupTag(pt.Tags, key)
+				return ok && v == value
+			}
+		}
+	case strings.HasPrefix(detail, string(PartialWriteUnparseable)):
+		e.Reason = PartialWriteUnparseable
+		unparseable := make(map[string]bool)
+		for _, m := range unparseableRegex.FindAllStringSubmatch(detail, -1) {
+			unparseable[m[1]] = true
+		}
+		match = func(text string, pt *Point, perr error) bool {
+			return unparseable[text]
+		}
+	}
+
+	if match == nil {
+		return e, len(data)
+	}
+	lines, dropped := findLines(data, match)
+	e.Lines = lines
+	return e, len(data) - dropped
+}
+
+// findLines decodes each point in the data and returns the line numbers of
+// the points that match along with the number of bytes used by those lines.
+func findLines(data []byte, match func(text string, pt *Point, perr error) bool) ([]int, int) {
+	var lines []int
+	var offsets []int
+	for i, b := range data {
+		if b == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	// lineEnd returns the offset after the line with the given line number.
+	lineEnd := func(n int) int {
+		if n == 0 {
+			return 0
+		} else if n > len(offsets) {
+			return len(data)
+		}
+		return offsets[n-1]
+	}
+
+	dec := &lineProtocolV1Decoder{
+		r:         bufio.NewReader(bytes.NewReader(data)),
+		precision: 1,
+	}
+	size := 0
+	for {
+		var pt Point
+		err := dec.Decode(&pt)
+		if err != nil {
+			if _, ok := err.(ErrParse); !ok {
+				break
+			}
+		}
+
+		if match(string(dec.line), &pt, err) {
+			lines = append(lines, dec.start)
+			size += lineEnd(dec.lineno) - lineEnd(dec.start-1)
+		}
+	}
+	return lines, size
+}
+
+// fieldTypeName returns the name the server uses for the type of a field value.
+func fieldTypeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "float"
+	case int64:
+		return "integer"
+	case uint64:
+		return "unsigned"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package influxdb_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func TestWriter_PartialWrite(t *testing.T) {
+	data := "cpu,host=server01 value=1i 10\n" +
+		"cpu,host=server02 value=2 20\n" +
+		"mem,host=server01 free=3i 30\n" +
+		"cpu,host=server03 value=4i 40\n"
+
+	for _, tt := range []struct {
+		name    string
+		message string
+		want    influxdb.ErrPartialWrite
+		n       int
+	}{
+		{
+			name:    "FieldTypeConflict",
+			message: `partial write: field type conflict: input field "value" on measurement "cpu" is type integer, already exists as type float dropped=2`,
+			want: influxdb.ErrPartialWrite{
+				Reason:  influxdb.PartialWriteFieldTypeConflict,
+				Dropped: 2,
+				Lines:   []int{1, 4},
+			},
+			n: len(data) - 60,
+		},
+		{
+			name:    "RetentionPolicy",
+			message: `partial write: points beyond retention policy dropped=3`,
+			want: influxdb.ErrPartialWrite{
+				Reason:  influxdb.PartialWriteRetentionPolicy,
+				Dropped: 3,
+			},
+			n: len(data),
+		},
+		{
+			name:    "MaxValuesPerTag",
+			message: `partial write: max-values-per-tag limit exceeded (100001/100000): measurement="cpu" tag="host" value="server02" dropped=1`,
+			want: influxdb.ErrPartialWrite{
+				Reason:  influxdb.PartialWriteMaxValuesPerTag,
+				Dropped: 1,
+				Lines:   []int{2},
+			},
+			n: len(data) - 29,
+		},
+		{
+			name:    "Unparseable",
+			message: `partial write: unable to parse 'mem,host=server01 free=3i 30': invalid field format: unable to parse 'cpu,host=server03 value=4i 40': bad timestamp dropped=2`,
+			want: influxdb.ErrPartialWrite{
+				Reason:  influxdb.PartialWriteUnparseable,
+				Dropped: 2,
+				Lines:   []int{3, 4},
+			},
+			n: len(data) - 59,
+		},
+		{
+			name:    "Unknown",
+			message: `partial write: something new`,
+			want:    influxdb.ErrPartialWrite{},
+			n:       len(data),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": tt.message})
+			}))
+			defer server.Close()
+
+			client, err := influxdb.NewClient(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			n, err := client.Writer().Write([]byte(data))
+			tt.want.Err = tt.message
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Fatalf("got error %#v; want %#v", err, tt.want)
+			}
+			if n != tt.n {
+				t.Fatalf("got %d bytes written; want %d", n, tt.n)
+			}
+		})
+	}
+}
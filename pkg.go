@@ -1,5 +1,7 @@
 package influxdb
 
+import "context"
+
 // DefaultClient is the default InfluxDB client.
 var DefaultClient = &Client{}
 
@@ -9,6 +11,12 @@ func Ping() (ServerInfo, error) {
 	return DefaultClient.Ping()
 }
 
+// PingContext sends a ping to the server using the context to cancel the
+// request.
+func PingContext(ctx context.Context) (ServerInfo, error) {
+	return DefaultClient.PingContext(ctx)
+}
+
 // Querier returns a struct that can be used to save query options and execute queries.
 func DefaultQuerier() *Querier {
 	return DefaultClient.Querier()
@@ -20,8 +28,20 @@ func Select(q interface{}, opts ...QueryOption) (Cursor, error) {
 	return DefaultClient.Select(q, opts...)
 }
 
+// SelectContext executes a query and parses the results from the stream. The
+// context will cancel the request and any reads from the returned Cursor.
+func SelectContext(ctx context.Context, q interface{}, opts ...QueryOption) (Cursor, error) {
+	return DefaultClient.SelectContext(ctx, q, opts...)
+}
+
 // Execute executes a query and returns if any error occurred.
 // To specify options, use Querier to create a Querier and set the options on that.
 func Execute(q interface{}, opts ...QueryOption) error {
 	return DefaultClient.Execute(q, opts...)
 }
+
+// ExecuteContext executes a query and returns if any error occurred. The
+// context will cancel the request.
+func ExecuteContext(ctx context.Context, q interface{}, opts ...QueryOption) error {
+	return DefaultClient.ExecuteContext(ctx, q, opts...)
+}
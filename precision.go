@@ -1,5 +1,10 @@
 package influxdb
 
+import (
+	"fmt"
+	"time"
+)
+
 // Precision is the requested precision.
 type Precision string
 
@@ -26,3 +31,35 @@ const (
 func (p Precision) String() string {
 	return string(p)
 }
+
+// factor returns the number of nanoseconds in one unit of the precision.
+// An empty or unknown precision is treated as nanoseconds.
+func (p Precision) factor() int64 {
+	switch p {
+	case PrecisionHour:
+		return int64(time.Hour)
+	case PrecisionMinute:
+		return int64(time.Minute)
+	case PrecisionSecond:
+		return int64(time.Second)
+	case PrecisionMillisecond:
+		return int64(time.Millisecond)
+	case PrecisionMicrosecond:
+		return int64(time.Microsecond)
+	default:
+		return 1
+	}
+}
+
+// v2 returns the precision as it is written for the InfluxDB 2.x API. The
+// 2.x API does not support minute or hour precision.
+func (p Precision) v2() (string, error) {
+	switch p {
+	case PrecisionNanosecond, PrecisionMillisecond, PrecisionSecond:
+		return string(p), nil
+	case PrecisionMicrosecond:
+		return "us", nil
+	default:
+		return "", fmt.Errorf("precision %q is not supported by the v2 api", p)
+	}
+}
@@ -1,11 +1,15 @@
 package influxdb
 
 import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // Protocol implements a protocol encoder.
@@ -17,6 +21,13 @@ type Protocol interface {
 	ContentType() string
 }
 
+// DecoderProtocol is implemented by a Protocol that can also decode points.
+type DecoderProtocol interface {
+	// NewDecoder returns a PointDecoder that reads points in this protocol
+	// format from the io.Reader.
+	NewDecoder(r io.Reader, opt DecodeOptions) PointDecoder
+}
+
 // LineProtocol holds the factory methods for different versions of the line protocol.
 var LineProtocol = struct {
 	V1 func() Protocol
@@ -37,6 +48,17 @@ var (
 // There is no guarantee that all options here are used by a protocol.
 type EncodeOptions struct {
 	Precision Precision
+
+	// Unsigned encodes unsigned integers with the unsigned integer type.
+	// The server must support unsigned integers to use this option.
+	// Otherwise, unsigned integers are encoded as signed integers and an
+	// error is returned if the value is too large.
+	Unsigned bool
+
+	// Sorted encodes the tags and fields sorted by key so the same point is
+	// always encoded the same way. Tags with a repeated key are reduced to
+	// the last value for that key.
+	Sorted bool
 }
 
 // Encode encodes a point using the DefaultWriteProtocol.
@@ -44,64 +66,50 @@ func Encode(w io.Writer, pt *Point) error {
 	return DefaultWriteProtocol.Encode(w, pt, EncodeOptions{})
 }
 
-type lineProtocolV1 struct{}
+// DecodeOptions keeps the extra options that maybe used when decoding points.
+// There is no guarantee that all options here are used by a protocol.
+type DecodeOptions struct {
+	// Precision is the precision of the timestamps being decoded.
+	// The default is nanoseconds.
+	Precision Precision
+}
 
-func (*lineProtocolV1) Encode(w io.Writer, pt *Point, opt EncodeOptions) error {
-	if len(pt.Fields) == 0 {
-		return ErrNoFields
-	}
-
-	precisionFactor := int64(1)
-	if opt.Precision != "" {
-		switch opt.Precision {
-		case PrecisionHour:
-			precisionFactor = int64(time.Hour)
-		case PrecisionMinute:
-			precisionFactor = int64(time.Minute)
-		case PrecisionSecond:
-			precisionFactor = int64(time.Second)
-		case PrecisionMillisecond:
-			precisionFactor = int64(time.Millisecond)
-		case PrecisionMicrosecond:
-			precisionFactor = int64(time.Microsecond)
-		case PrecisionNanosecond:
-			// no change in the precision factor is needed
-		}
-	}
+// PointDecoder reads points from a stream.
+type PointDecoder interface {
+	// Decode reads the next point from the stream into pt. It returns
+	// io.EOF when there are no more points to read.
+	Decode(pt *Point) error
+}
 
-	io.WriteString(w, escapeMeasurement(pt.Name))
-	if len(pt.Tags) > 0 {
-		for _, t := range pt.Tags {
-			io.WriteString(w, ",")
-			io.WriteString(w, escapeTag(t.Key))
-			io.WriteString(w, "=")
-			io.WriteString(w, escapeTag(t.Value))
-		}
+// NewDecoder returns a PointDecoder for the DefaultWriteProtocol. If the
+// DefaultWriteProtocol does not implement DecoderProtocol, the line protocol
+// is decoded.
+func NewDecoder(r io.Reader) PointDecoder {
+	if p, ok := DefaultWriteProtocol.(DecoderProtocol); ok {
+		return p.NewDecoder(r, DecodeOptions{})
 	}
-	io.WriteString(w, " ")
+	return (*lineProtocolV1)(nil).NewDecoder(r, DecodeOptions{})
+}
 
-	i := 0
-	for k, v := range pt.Fields {
-		if i > 0 {
-			io.WriteString(w, ",")
-		}
-		io.WriteString(w, escapeString(k))
-		io.WriteString(w, "=")
+type lineProtocolV1 struct{}
 
-		value, err := formatValue(v)
-		if err != nil {
-			return err
-		}
-		io.WriteString(w, value)
-		i++
+func (*lineProtocolV1) Encode(w io.Writer, pt *Point, opt EncodeOptions) error {
+	enc := encoderPool.Get().(*Encoder)
+	defer encoderPool.Put(enc)
+	enc.opt = opt
+
+	// Encode directly into the unused capacity of a bytes.Buffer.
+	if buf, ok := w.(*bytes.Buffer); ok {
+		return enc.EncodeTo(buf, pt)
 	}
-	if !pt.Time.IsZero() {
-		io.WriteString(w, " ")
-		ts := pt.Time.UnixNano() / precisionFactor
-		io.WriteString(w, strconv.FormatInt(ts, 10))
+
+	var err error
+	enc.buf, err = enc.AppendPoint(enc.buf[:0], pt)
+	if err != nil {
+		return err
 	}
-	io.WriteString(w, "\n")
-	return nil
+	_, err = w.Write(enc.buf)
+	return err
 }
 
 func (*lineProtocolV1) ContentType() string {
@@ -125,56 +133,164 @@ var (
 		{s: `=`, esc: `\=`},
 	}
 
+	// fieldKeyEscapeCodes are the same as the tag escape codes. Field keys
+	// are escaped like tag keys and not like string field values.
+	fieldKeyEscapeCodes = tagEscapeCodes
+
 	stringEscapeCodes = []escapeSequence{
 		{s: `\`, esc: `\\`},
 		{s: `"`, esc: `\"`},
 	}
 )
 
-// escapeMeasurement escapes a measurement.
-func escapeMeasurement(in string) string {
-	return escape(in, measurementEscapeCodes)
-}
+// The characters that must be escaped for each part of a point.
+var (
+	measurementEscapeChars = escapeChars(measurementEscapeCodes)
+	tagEscapeChars         = escapeChars(tagEscapeCodes)
+	fieldKeyEscapeChars    = escapeChars(fieldKeyEscapeCodes)
+	stringEscapeChars      = escapeChars(stringEscapeCodes)
 
-// escapeTag escapes a tag key or value.
-func escapeTag(in string) string {
-	return escape(in, tagEscapeCodes)
+	// identifierEscapeChars are the characters that cannot follow a
+	// backslash in a measurement, tag, or field key. Some parsers also
+	// treat a backslash before a quote as an escape sequence.
+	identifierEscapeChars = tagEscapeChars + `"`
+)
+
+// escapeChars returns the characters escaped by the escape sequences. Every
+// escape sequence is the character preceded by a backslash.
+func escapeChars(codes []escapeSequence) string {
+	chars := make([]byte, len(codes))
+	for i, c := range codes {
+		chars[i] = c.s[0]
+	}
+	return string(chars)
 }
 
-// escapeString escapes a string field key or value.
-func escapeString(in string) string {
-	return escape(in, stringEscapeCodes)
+// appendEscaped appends the string to the buffer with a backslash before each
+// of the escaped characters. Strings without any of the characters are
+// appended as is.
+func appendEscaped(dst []byte, s string, chars string) []byte {
+	if strings.IndexAny(s, chars) < 0 {
+		return append(dst, s...)
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(chars, s[i]) >= 0 {
+			dst = append(dst, '\\')
+		}
+		dst = append(dst, s[i])
+	}
+	return dst
 }
 
-// escape the string with the given escape sequences.
-func escape(in string, codes []escapeSequence) string {
-	for _, c := range codes {
-		in = strings.Replace(in, c.s, c.esc, -1)
+// checkIdentifier checks if a measurement name, tag key, tag value, or field
+// key can be represented in the line protocol. If it cannot, the reason is
+// returned. There is no escape sequence for a newline or a backslash. A
+// trailing backslash would escape the delimiter that follows it and a
+// backslash before a character that gets escaped would be read as part of
+// the escape sequence.
+func checkIdentifier(s string) string {
+	if s == "" {
+		return "is empty"
+	} else if strings.IndexByte(s, '\n') >= 0 {
+		return "contains a newline"
+	} else if s[len(s)-1] == '\\' {
+		return "ends with a backslash"
+	}
+	for i := strings.IndexByte(s, '\\'); i >= 0 && i < len(s)-1; i++ {
+		if s[i] == '\\' && strings.IndexByte(identifierEscapeChars, s[i+1]) >= 0 {
+			return "contains a backslash before " + strconv.Quote(s[i+1:i+2])
+		}
 	}
-	return in
+	return ""
 }
 
-// formatValue formats a value as a string.
-func formatValue(v interface{}) (string, error) {
+// errNilValue is returned by appendValue when the value is nil.
+var errNilValue = errors.New("nil value")
+
+// appendValue appends the value in the line protocol format. Floats are
+// formatted with the fewest digits that parse back to the same value. Types
+// that are not one of the basic types are formatted using their underlying
+// kind.
+func appendValue(dst []byte, v interface{}, opt EncodeOptions) ([]byte, error) {
 	switch v := v.(type) {
 	case float64:
-		return strconv.FormatFloat(v, 'g', 6, 64), nil
+		return appendFloat(dst, v, 64)
 	case float32:
-		return strconv.FormatFloat(float64(v), 'g', 6, 64), nil
+		return appendFloat(dst, float64(v), 32)
 	case int64:
-		return strconv.FormatInt(v, 10) + "i", nil
+		return append(strconv.AppendInt(dst, v, 10), 'i'), nil
 	case int32:
-		return strconv.FormatInt(int64(v), 10) + "i", nil
+		return append(strconv.AppendInt(dst, int64(v), 10), 'i'), nil
 	case int:
-		return strconv.Itoa(v) + "i", nil
+		return append(strconv.AppendInt(dst, int64(v), 10), 'i'), nil
+	case uint64:
+		return appendUnsigned(dst, v, opt)
 	case string:
-		return `"` + escapeString(v) + `"`, nil
+		return appendString(dst, v), nil
+	case []byte:
+		return appendString(dst, string(v)), nil
 	case bool:
-		if v {
-			return "true", nil
+		return strconv.AppendBool(dst, v), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return dst, errNilValue
+	}
+
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return dst, err
+		}
+		return appendValue(dst, value, opt)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		return appendValue(dst, rv.Elem().Interface(), opt)
+	case reflect.Float32:
+		return appendFloat(dst, rv.Float(), 32)
+	case reflect.Float64:
+		return appendFloat(dst, rv.Float(), 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return append(strconv.AppendInt(dst, rv.Int(), 10), 'i'), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendUnsigned(dst, rv.Uint(), opt)
+	case reflect.String:
+		return appendString(dst, rv.String()), nil
+	case reflect.Bool:
+		return strconv.AppendBool(dst, rv.Bool()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return appendString(dst, string(rv.Bytes())), nil
 		}
-		return "false", nil
-	default:
-		return "", fmt.Errorf("invalid field type: %T", v)
 	}
+	return dst, fmt.Errorf("invalid field type: %T", v)
+}
+
+// appendString appends a quoted and escaped string field value.
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	dst = appendEscaped(dst, s, stringEscapeChars)
+	return append(dst, '"')
+}
+
+// appendFloat appends a float with the given bit size.
+func appendFloat(dst []byte, v float64, bitSize int) ([]byte, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return dst, ErrInvalidFloat{Value: v}
+	}
+	return strconv.AppendFloat(dst, v, 'g', -1, bitSize), nil
+}
+
+// appendUnsigned appends an unsigned integer. If unsigned integers are not
+// enabled, the value is encoded as a signed integer if it is in range.
+func appendUnsigned(dst []byte, v uint64, opt EncodeOptions) ([]byte, error) {
+	if opt.Unsigned {
+		return append(strconv.AppendUint(dst, v, 10), 'u'), nil
+	} else if v > math.MaxInt64 {
+		return dst, fmt.Errorf("unsigned value %d overflows int64", v)
+	}
+	return append(strconv.AppendUint(dst, v, 10), 'i'), nil
 }
@@ -0,0 +1,298 @@
+package influxdb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errUnterminatedString is returned by parseLine when a string field value
+// continues past the end of the buffer.
+var errUnterminatedString = errors.New("unterminated string")
+
+func (*lineProtocolV1) NewDecoder(r io.Reader, opt DecodeOptions) PointDecoder {
+	return &lineProtocolV1Decoder{
+		r:         bufio.NewReader(r),
+		precision: opt.Precision.factor(),
+	}
+}
+
+type lineProtocolV1Decoder struct {
+	r         *bufio.Reader
+	precision int64
+	lineno    int
+	start     int
+	buf       []byte
+	line      []byte
+	err       error
+}
+
+// Decode reads the next point from the stream. Blank lines and comments are
+// skipped. If a point cannot be parsed, an ErrParse is returned and the next
+// call to Decode continues with the following line.
+func (d *lineProtocolV1Decoder) Decode(pt *Point) error {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return err
+		}
+
+		// Skip blank lines and comments.
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		d.start = d.lineno
+		d.line = append(d.line[:0], trimmed...)
+
+		// A string field value may contain a newline so keep reading lines
+		// until the string has been terminated.
+		for {
+			err = parseLine(d.line, d.precision, pt)
+			if err != errUnterminatedString {
+				break
+			}
+
+			next, rerr := d.readLine()
+			if rerr == io.EOF {
+				break
+			} else if rerr != nil {
+				return rerr
+			}
+			d.line = append(append(d.line, '\n'), next...)
+		}
+
+		if err != nil {
+			return ErrParse{Line: d.start, Text: string(d.line), Reason: err.Error()}
+		}
+		return nil
+	}
+}
+
+// readLine reads the next line without the line ending. The returned slice
+// is only valid until the next call to readLine.
+func (d *lineProtocolV1Decoder) readLine() ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	line, err := d.r.ReadBytes('\n')
+	if err != nil {
+		if err != io.EOF || len(line) == 0 {
+			d.err = err
+			return nil, err
+		}
+		d.err = io.EOF
+	}
+	d.lineno++
+
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	d.buf = append(d.buf[:0], line...)
+	return d.buf, nil
+}
+
+// parseLine parses a single point from the line.
+func parseLine(line []byte, precision int64, pt *Point) error {
+	var p Point
+
+	// Read the measurement name.
+	i := scanTo(line, 0, ", ")
+	if i == 0 {
+		return errors.New("missing measurement")
+	}
+	p.Name = unescape(line[:i], measurementEscapeCodes)
+
+	// Read the tags.
+	for i < len(line) && line[i] == ',' {
+		start := i + 1
+		i = scanTo(line, start, "=, ")
+		if i == len(line) || line[i] != '=' {
+			return errors.New("missing tag value")
+		} else if i == start {
+			return errors.New("missing tag key")
+		}
+		key := unescape(line[start:i], tagEscapeCodes)
+
+		start = i + 1
+		i = scanTo(line, start, ", ")
+		if i == start {
+			return errors.New("missing tag value")
+		}
+		p.Tags = append(p.Tags, Tag{Key: key, Value: unescape(line[start:i], tagEscapeCodes)})
+	}
+
+	// Read the fields.
+	i = skipSpaces(line, i)
+	if i == len(line) {
+		return errors.New("missing fields")
+	}
+	p.Fields = make(map[string]interface{})
+	for {
+		start := i
+		i = scanTo(line, start, "=, ")
+		if i == len(line) || line[i] != '=' {
+			return errors.New("missing field value")
+		} else if i == start {
+			return errors.New("missing field key")
+		}
+		key := unescape(line[start:i], fieldKeyEscapeCodes)
+
+		start = i + 1
+		var value interface{}
+		if start < len(line) && line[start] == '"' {
+			end := scanString(line, start+1)
+			if end < 0 {
+				return errUnterminatedString
+			}
+			value = unescape(line[start+1:end], stringEscapeCodes)
+			i = end + 1
+		} else {
+			i = scanTo(line, start, ", ")
+			v, err := parseFieldValue(line[start:i])
+			if err != nil {
+				return err
+			} else if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				// The server does not accept these and they cannot be
+				// encoded again.
+				return ErrInvalidFloat{Field: key, Value: f}
+			}
+			value = v
+		}
+		p.Fields[key] = value
+
+		if i == len(line) || line[i] != ',' {
+			break
+		}
+		i++
+	}
+
+	// Read the optional timestamp.
+	if i < len(line) && line[i] != ' ' {
+		return errors.New("invalid field format")
+	}
+	i = skipSpaces(line, i)
+	if i < len(line) {
+		end := scanTo(line, i, " ")
+		ts, err := strconv.ParseInt(string(line[i:end]), 10, 64)
+		if err != nil {
+			return errors.New("invalid timestamp")
+		}
+		if ts > math.MaxInt64/precision || ts < math.MinInt64/precision {
+			return errors.New("timestamp out of range")
+		}
+		p.Time = time.Unix(0, ts*precision).UTC()
+
+		if skipSpaces(line, end) != len(line) {
+			return errors.New("unexpected data after timestamp")
+		}
+	}
+
+	*pt = p
+	return nil
+}
+
+// parseFieldValue parses an unquoted field value.
+func parseFieldValue(b []byte) (interface{}, error) {
+	if len(b) == 0 {
+		return nil, errors.New("missing field value")
+	}
+
+	s := string(b)
+	switch s {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	switch s[len(s)-1] {
+	case 'i':
+		v, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid integer")
+		}
+		return v, nil
+	case 'u':
+		v, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid unsigned integer")
+		}
+		return v, nil
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, errors.New("invalid number")
+	}
+	return v, nil
+}
+
+// scanTo returns the index of the first unescaped byte in delims starting at
+// start. If none is found, the length of the buffer is returned.
+func scanTo(b []byte, start int, delims string) int {
+	for i := start; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '\\':
+			i++
+		case strings.IndexByte(delims, c) >= 0:
+			return i
+		}
+	}
+	return len(b)
+}
+
+// scanString returns the index of the closing quote of a string that starts
+// at start. If the string is not terminated, this returns -1.
+func scanString(b []byte, start int) int {
+	for i := start; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// skipSpaces returns the index of the first byte that is not a space.
+func skipSpaces(b []byte, i int) int {
+	for i < len(b) && b[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// unescape replaces the escape sequences in the buffer. Backslashes that are
+// not part of an escape sequence are kept as is.
+func unescape(b []byte, codes []escapeSequence) string {
+	if bytes.IndexByte(b, '\\') < 0 {
+		return string(b)
+	}
+
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\\' && i+1 < len(b) {
+			matched := false
+			for _, c := range codes {
+				if c.esc[1] == b[i+1] {
+					out = append(out, c.s...)
+					matched = true
+					break
+				}
+			}
+			if matched {
+				i++
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,181 @@
+package influxdb_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+// decodeAll reads every point from the decoder.
+func decodeAll(t *testing.T, dec influxdb.PointDecoder) []influxdb.Point {
+	var points []influxdb.Point
+	for {
+		var pt influxdb.Point
+		if err := dec.Decode(&pt); err == io.EOF {
+			return points
+		} else if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		points = append(points, pt)
+	}
+}
+
+func TestLineProtocol_V1_Decode(t *testing.T) {
+	r := strings.NewReader(`# a comment
+cpu,host=server01,region=us-west value=2.5,count=3i,total=5u,ok=t,status="running" 1000
+
+  # an indented comment
+mem free=false
+`)
+	points := decodeAll(t, influxdb.NewDecoder(r))
+
+	want := []influxdb.Point{
+		{
+			Name: "cpu",
+			Tags: influxdb.Tags{
+				{Key: "host", Value: "server01"},
+				{Key: "region", Value: "us-west"},
+			},
+			Fields: map[string]interface{}{
+				"value":  2.5,
+				"count":  int64(3),
+				"total":  uint64(5),
+				"ok":     true,
+				"status": "running",
+			},
+			Time: time.Unix(0, 1000).UTC(),
+		},
+		{
+			Name:   "mem",
+			Fields: map[string]interface{}{"free": false},
+		},
+	}
+	if !reflect.DeepEqual(points, want) {
+		t.Fatalf("got %#v; want %#v", points, want)
+	}
+}
+
+func TestLineProtocol_V1_Decode_Escapes(t *testing.T) {
+	r := strings.NewReader(`c\,p\ u,ho\=st=server\ 01,region=us\,west fi\ eld="say \"hi\" \\ bye",path="C:\dir"` + "\r\n")
+	points := decodeAll(t, influxdb.NewDecoder(r))
+
+	want := []influxdb.Point{{
+		Name: "c,p u",
+		Tags: influxdb.Tags{
+			{Key: "ho=st", Value: "server 01"},
+			{Key: "region", Value: "us,west"},
+		},
+		Fields: map[string]interface{}{
+			"fi eld": `say "hi" \ bye`,
+			"path":   `C:\dir`,
+		},
+	}}
+	if !reflect.DeepEqual(points, want) {
+		t.Fatalf("got %#v; want %#v", points, want)
+	}
+}
+
+func TestLineProtocol_V1_Decode_MultilineString(t *testing.T) {
+	r := strings.NewReader("log msg=\"first\n# not a comment\nlast\" 5\ncpu value=1\n")
+	points := decodeAll(t, influxdb.NewDecoder(r))
+
+	if got, want := len(points), 2; got != want {
+		t.Fatalf("got %d points; want %d", got, want)
+	}
+	if got, want := points[0].Fields["msg"], "first\n# not a comment\nlast"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	if got, want := points[1].Name, "cpu"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestLineProtocol_V1_Decode_Precision(t *testing.T) {
+	dec := influxdb.LineProtocol.V1().(influxdb.DecoderProtocol).NewDecoder(strings.NewReader("cpu value=1 10\n"), influxdb.DecodeOptions{
+		Precision: influxdb.PrecisionSecond,
+	})
+	points := decodeAll(t, dec)
+
+	if got, want := points[0].Time, time.Unix(10, 0); !got.Equal(want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestLineProtocol_V1_Decode_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		line   string
+		reason string
+	}{
+		{line: ",host=a value=1", reason: "missing measurement"},
+		{line: "cpu,host value=1", reason: "missing tag value"},
+		{line: "cpu,=a value=1", reason: "missing tag key"},
+		{line: "cpu", reason: "missing fields"},
+		{line: "cpu value", reason: "missing field value"},
+		{line: "cpu =1", reason: "missing field key"},
+		{line: "cpu value=1x", reason: "invalid number"},
+		{line: "cpu value=NaN", reason: `invalid value for field "value": NaN`},
+		{line: "cpu value=-Inf", reason: `invalid value for field "value": -Inf`},
+		{line: "cpu value=1.5i", reason: "invalid integer"},
+		{line: "cpu value=-1u", reason: "invalid unsigned integer"},
+		{line: `cpu value="a"b`, reason: "invalid field format"},
+		{line: "cpu value=1 abc", reason: "invalid timestamp"},
+		{line: "cpu value=1 10 20", reason: "unexpected data after timestamp"},
+		{line: `cpu value="open`, reason: "unterminated string"},
+	} {
+		dec := influxdb.NewDecoder(strings.NewReader("# header\n" + tt.line + "\ncpu value=2\n"))
+
+		// The rest of the input is consumed while looking for the end of an
+		// unterminated string.
+		text := tt.line
+		if tt.reason == "unterminated string" {
+			text += "\ncpu value=2"
+		}
+
+		var pt influxdb.Point
+		if err, want := dec.Decode(&pt), (influxdb.ErrParse{Line: 2, Text: text, Reason: tt.reason}); err != want {
+			t.Errorf("%s: got error %#v; want %#v", tt.line, err, want)
+			continue
+		} else if text != tt.line {
+			continue
+		}
+
+		// The decoder should continue with the next line.
+		if err := dec.Decode(&pt); err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.line, err)
+		} else if got, want := pt.Fields["value"], float64(2); got != want {
+			t.Errorf("%s: got %v; want %v", tt.line, got, want)
+		}
+	}
+}
+
+func TestLineProtocol_V1_RoundTrip(t *testing.T) {
+	pt := influxdb.Point{
+		Name: "cpu load,total",
+		Tags: influxdb.Tags{
+			{Key: "host name", Value: "server=01"},
+			{Key: "region", Value: "us,west"},
+		},
+		Fields: map[string]interface{}{
+			"value":    float64(5),
+			"count":    int64(-3),
+			"quoted\"": `a "quoted\string"`,
+			"enabled":  true,
+		},
+		Time: time.Unix(0, 1500).UTC(),
+	}
+
+	var buf bytes.Buffer
+	if err := influxdb.Encode(&buf, &pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	points := decodeAll(t, influxdb.NewDecoder(&buf))
+	if got, want := points, []influxdb.Point{pt}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
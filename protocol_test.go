@@ -2,7 +2,13 @@ package influxdb_test
 
 import (
 	"bytes"
+	"database/sql"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 
 	influxdb "github.com/influxdata/influxdb-client"
@@ -49,3 +55,400 @@ func TestLineProtocol_V1(t *testing.T) {
 		t.Errorf("unexpected protocol output:\n\ngot=%v\nwant=%v\n", got, want)
 	}
 }
+
+func TestLineProtocol_V1_Floats(t *testing.T) {
+	for _, tt := range []struct {
+		value interface{}
+		want  string
+	}{
+		{value: 123.456789, want: "123.456789"},
+		{value: 1234567890.5, want: "1.2345678905e+09"},
+		{value: 0.1, want: "0.1"},
+		{value: float64(-2), want: "-2"},
+		{value: math.MaxFloat64, want: "1.7976931348623157e+308"},
+		{value: math.SmallestNonzeroFloat64, want: "5e-324"},
+		{value: float32(0.1), want: "0.1"},
+		{value: float32(16777216), want: "1.6777216e+07"},
+	} {
+		var buf bytes.Buffer
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": tt.value},
+		}
+		if err := influxdb.Encode(&buf, &pt); err != nil {
+			t.Errorf("%v: unexpected error: %s", tt.value, err)
+		} else if got, want := buf.String(), "cpu value="+tt.want+"\n"; got != want {
+			t.Errorf("%v: got %q; want %q", tt.value, got, want)
+		}
+	}
+}
+
+func TestLineProtocol_V1_InvalidFloat(t *testing.T) {
+	for _, v := range []interface{}{
+		math.NaN(),
+		math.Inf(1),
+		math.Inf(-1),
+		float32(math.Inf(1)),
+	} {
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": v},
+		}
+		err := influxdb.Encode(&bytes.Buffer{}, &pt)
+		if e, ok := err.(influxdb.ErrInvalidFloat); !ok {
+			t.Errorf("%v: got error %#v; want %T", v, err, influxdb.ErrInvalidFloat{})
+		} else if e.Field != "value" {
+			t.Errorf("%v: got field %q; want %q", v, e.Field, "value")
+		}
+	}
+}
+
+// encodeFloatField encodes a point with a single float field and returns the
+// text of the field value.
+func encodeFloatField(t *testing.T, v interface{}) string {
+	var buf bytes.Buffer
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": v},
+	}
+	if err := influxdb.Encode(&buf, &pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(buf.String(), "cpu value="), "\n")
+}
+
+func TestLineProtocol_V1_Float64_RoundTrip(t *testing.T) {
+	f := func(bits uint64) bool {
+		v := math.Float64frombits(bits)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return true
+		}
+
+		var buf bytes.Buffer
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": v},
+		}
+		if err := influxdb.Encode(&buf, &pt); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var got influxdb.Point
+		if err := influxdb.NewDecoder(&buf).Decode(&got); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		f, ok := got.Fields["value"].(float64)
+		return ok && math.Float64bits(f) == bits
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLineProtocol_V1_Float32_RoundTrip(t *testing.T) {
+	f := func(bits uint32) bool {
+		v := math.Float32frombits(bits)
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return true
+		}
+
+		s := encodeFloatField(t, v)
+		f, err := strconv.ParseFloat(s, 32)
+		return err == nil && math.Float32bits(float32(f)) == bits
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type celsius float64
+
+type state string
+
+func TestLineProtocol_V1_FieldTypes(t *testing.T) {
+	i := int16(7)
+	for _, tt := range []struct {
+		value interface{}
+		opt   influxdb.EncodeOptions
+		want  string
+	}{
+		{value: int8(-8), want: "-8i"},
+		{value: int16(16), want: "16i"},
+		{value: uint(1), want: "1i"},
+		{value: uint8(8), want: "8i"},
+		{value: uint32(32), want: "32i"},
+		{value: uint64(math.MaxInt64), want: "9223372036854775807i"},
+		{value: uint64(math.MaxUint64), opt: influxdb.EncodeOptions{Unsigned: true}, want: "18446744073709551615u"},
+		{value: uint16(5), opt: influxdb.EncodeOptions{Unsigned: true}, want: "5u"},
+		{value: 90 * time.Second, want: "90000000000i"},
+		{value: []byte("raw"), want: `"raw"`},
+		{value: celsius(21.5), want: "21.5"},
+		{value: state("on"), want: `"on"`},
+		{value: &i, want: "7i"},
+		{value: sql.NullInt64{Int64: 3, Valid: true}, want: "3i"},
+		{value: sql.NullString{String: "ok", Valid: true}, want: `"ok"`},
+	} {
+		var buf bytes.Buffer
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": tt.value},
+		}
+		if err := influxdb.LineProtocol.V1().Encode(&buf, &pt, tt.opt); err != nil {
+			t.Errorf("%T(%v): unexpected error: %s", tt.value, tt.value, err)
+		} else if got, want := buf.String(), "cpu value="+tt.want+"\n"; got != want {
+			t.Errorf("%T(%v): got %q; want %q", tt.value, tt.value, got, want)
+		}
+	}
+}
+
+func TestLineProtocol_V1_NilFields(t *testing.T) {
+	var missing *float64
+	pt := influxdb.Point{
+		Name: "cpu",
+		Fields: map[string]interface{}{
+			"value":   float64(1),
+			"missing": missing,
+			"null":    sql.NullFloat64{},
+			"none":    nil,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := influxdb.Encode(&buf, &pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if got, want := buf.String(), "cpu value=1\n"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	delete(pt.Fields, "value")
+	if err := influxdb.Encode(&bytes.Buffer{}, &pt); err != influxdb.ErrNoFields {
+		t.Fatalf("got error %v; want %v", err, influxdb.ErrNoFields)
+	}
+}
+
+func TestLineProtocol_V1_UnsignedOverflow(t *testing.T) {
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": uint64(math.MaxInt64 + 1)},
+	}
+	if err := influxdb.Encode(&bytes.Buffer{}, &pt); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestLineProtocol_V1_InvalidFieldType(t *testing.T) {
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": []int{1}},
+	}
+	if err := influxdb.Encode(&bytes.Buffer{}, &pt); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestLineProtocol_V1_Sorted(t *testing.T) {
+	pt := influxdb.Point{
+		Name: "cpu",
+		Tags: influxdb.Tags{
+			{Key: "region", Value: "us-west"},
+			{Key: "host", Value: "server01"},
+			{Key: "az", Value: "a"},
+			{Key: "host", Value: "server02"},
+		},
+		Fields: map[string]interface{}{
+			"user":   float64(1),
+			"system": float64(2),
+			"idle":   float64(3),
+			"nice":   float64(4),
+		},
+		Time: time.Unix(0, 1000),
+	}
+	opt := influxdb.EncodeOptions{Sorted: true}
+
+	want := "cpu,az=a,host=server02,region=us-west idle=3,nice=4,system=2,user=1 1000\n"
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		if err := influxdb.LineProtocol.V1().Encode(&buf, &pt, opt); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if got := buf.String(); got != want {
+			t.Fatalf("got %q; want %q", got, want)
+		}
+	}
+
+	// The tags of the point should not be modified.
+	if got, want := pt.Tags[0].Key, "region"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestLineProtocol_V1_Sorted_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations are not accurate with the race detector")
+	}
+
+	p := influxdb.LineProtocol.V1()
+	for _, tags := range []influxdb.Tags{
+		{{Key: "az", Value: "a"}, {Key: "host", Value: "server01"}, {Key: "region", Value: "us-west"}},
+		{{Key: "region", Value: "us-west"}, {Key: "host", Value: "server01"}, {Key: "az", Value: "a"}},
+	} {
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Tags:   tags,
+			Fields: map[string]interface{}{"user": int64(1), "system": int64(2)},
+		}
+
+		var buf bytes.Buffer
+		encode := func(opt influxdb.EncodeOptions) func() {
+			return func() {
+				buf.Reset()
+				p.Encode(&buf, &pt, opt)
+			}
+		}
+		unsorted := testing.AllocsPerRun(100, encode(influxdb.EncodeOptions{}))
+		sorted := testing.AllocsPerRun(100, encode(influxdb.EncodeOptions{Sorted: true}))
+
+		// The scratch buffers are pooled so sorting should not allocate once
+		// they have grown.
+		if sorted != unsorted {
+			t.Errorf("%s: sorted encoding allocated %v times per run; want %v", tags, sorted, unsorted)
+		}
+	}
+}
+
+func TestLineProtocol_V1_Escaping(t *testing.T) {
+	pt := influxdb.Point{
+		Name: "cpu,load =1",
+		Tags: influxdb.Tags{
+			{Key: "host name", Value: `server\01`},
+			{Key: "path=a,b", Value: `#"quoted"`},
+		},
+		Fields: map[string]interface{}{
+			`usage "user", total=`: "line one\nline \"two\" \\",
+		},
+	}
+	var buf bytes.Buffer
+	if err := influxdb.Encode(&buf, &pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `cpu\,load\ =1,host\ name=server\01,path\=a\,b=#"quoted" usage\ "user"\,\ total\==` +
+		`"line one` + "\n" + `line \"two\" \\"` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	points := decodeAll(t, influxdb.NewDecoder(&buf))
+	if got, want := points, []influxdb.Point{pt}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
+
+func TestLineProtocol_V1_Backslashes(t *testing.T) {
+	pt := influxdb.Point{
+		Name: `C:\Program Files\cpu`,
+		Tags: influxdb.Tags{
+			{Key: `dir\name`, Value: `C:\Program Files\\data`},
+		},
+		Fields: map[string]interface{}{
+			`a\b c`: `C:\ dir\`,
+		},
+	}
+	var buf bytes.Buffer
+	if err := influxdb.Encode(&buf, &pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `C:\Program\ Files\cpu,dir\name=C:\Program\ Files\\data a\b\ c="C:\\ dir\\"` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+
+	points := decodeAll(t, influxdb.NewDecoder(&buf))
+	if got, want := points, []influxdb.Point{pt}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
+
+func TestLineProtocol_V1_EmptyTagValue(t *testing.T) {
+	pt := influxdb.Point{
+		Name: "cpu",
+		Tags: influxdb.Tags{
+			{Key: "host", Value: ""},
+			{Key: "region", Value: "us-west"},
+		},
+		Fields: map[string]interface{}{"value": int64(1)},
+	}
+	var buf bytes.Buffer
+	if err := influxdb.Encode(&buf, &pt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if got, want := buf.String(), "cpu,region=us-west value=1i\n"; got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
+
+func TestLineProtocol_V1_InvalidPoint(t *testing.T) {
+	for _, tt := range []struct {
+		pt   influxdb.Point
+		want influxdb.ErrInvalidPoint
+	}{
+		{
+			pt:   influxdb.Point{Name: "", Fields: map[string]interface{}{"value": 1}},
+			want: influxdb.ErrInvalidPoint{Reason: "measurement is empty"},
+		},
+		{
+			pt:   influxdb.Point{Name: "#cpu", Fields: map[string]interface{}{"value": 1}},
+			want: influxdb.ErrInvalidPoint{Name: "#cpu", Reason: "measurement begins with #"},
+		},
+		{
+			pt:   influxdb.Point{Name: "cpu\nmem", Fields: map[string]interface{}{"value": 1}},
+			want: influxdb.ErrInvalidPoint{Name: "cpu\nmem", Reason: "measurement contains a newline"},
+		},
+		{
+			pt:   influxdb.Point{Name: `cpu\`, Fields: map[string]interface{}{"value": 1}},
+			want: influxdb.ErrInvalidPoint{Name: `cpu\`, Reason: "measurement ends with a backslash"},
+		},
+		{
+			pt: influxdb.Point{
+				Name:   "cpu",
+				Tags:   influxdb.Tags{{Key: "", Value: "server01"}},
+				Fields: map[string]interface{}{"value": 1},
+			},
+			want: influxdb.ErrInvalidPoint{Name: "cpu", Reason: "tag key is empty"},
+		},
+		{
+			pt: influxdb.Point{
+				Name:   "cpu",
+				Tags:   influxdb.Tags{{Key: "host", Value: "server01\n"}},
+				Fields: map[string]interface{}{"value": 1},
+			},
+			want: influxdb.ErrInvalidPoint{Name: "cpu", Key: "host", Reason: "tag value contains a newline"},
+		},
+		{
+			pt: influxdb.Point{
+				Name:   "cpu",
+				Tags:   influxdb.Tags{{Key: "path", Value: `C:\ dir`}},
+				Fields: map[string]interface{}{"value": 1},
+			},
+			want: influxdb.ErrInvalidPoint{Name: "cpu", Key: "path", Reason: `tag value contains a backslash before " "`},
+		},
+		{
+			pt:   influxdb.Point{Name: `cpu\,load`, Fields: map[string]interface{}{"value": 1}},
+			want: influxdb.ErrInvalidPoint{Name: `cpu\,load`, Reason: `measurement contains a backslash before ","`},
+		},
+		{
+			pt:   influxdb.Point{Name: "cpu", Fields: map[string]interface{}{"val\nue": 1}},
+			want: influxdb.ErrInvalidPoint{Name: "cpu", Key: "val\nue", Reason: "field key contains a newline"},
+		},
+		{
+			pt:   influxdb.Point{Name: "cpu", Fields: map[string]interface{}{`value\`: 1}},
+			want: influxdb.ErrInvalidPoint{Name: "cpu", Key: `value\`, Reason: "field key ends with a backslash"},
+		},
+	} {
+		var buf bytes.Buffer
+		if err := influxdb.Encode(&buf, &tt.pt); err != tt.want {
+			t.Errorf("got error %#v; want %#v", err, tt.want)
+		} else if buf.Len() != 0 {
+			t.Errorf("%s: invalid point was partially written: %q", err, buf.String())
+		}
+	}
+}
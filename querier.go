@@ -1,5 +1,7 @@
 package influxdb
 
+import "context"
+
 // QueryOptions is a set of configuration options for configuring queries.
 type QueryOptions struct {
 	Database  string
@@ -9,6 +11,10 @@ type QueryOptions struct {
 	Format    string
 	Async     bool
 	Params    map[string]interface{}
+
+	// Compression asks the server to compress the response. The response
+	// is decompressed as it is read by the Cursor.
+	Compression Compression
 }
 
 // Clone creates a copy of the QueryOptions.
@@ -30,6 +36,13 @@ type Querier struct {
 // Select executes a query with GET and returns a Cursor that will parse the
 // results from the stream. Use Execute for any queries that modify the database.
 func (q *Querier) Select(query interface{}, opts ...QueryOption) (Cursor, error) {
+	return q.SelectContext(context.Background(), query, opts...)
+}
+
+// SelectContext executes a query with GET and returns a Cursor that will parse
+// the results from the stream. If the context is cancelled, the request is
+// aborted and any in-progress read from the Cursor returns the context error.
+func (q *Querier) SelectContext(ctx context.Context, query interface{}, opts ...QueryOption) (Cursor, error) {
 	opt := q.QueryOptions
 	if len(opts) > 0 {
 		opt = opt.Clone()
@@ -38,23 +51,39 @@ func (q *Querier) Select(query interface{}, opts ...QueryOption) (Cursor, error)
 		}
 	}
 
-	req, err := q.c.NewReadonlyQueryRequest(query, opt)
+	req, err := q.c.NewReadonlyQueryRequestContext(ctx, query, opt)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := q.c.Client.Do(req)
+	resp, err := q.c.do(req, true)
 	if err != nil {
 		return nil, err
 	} else if resp.StatusCode/100 != 2 {
 		return nil, ReadError(resp)
 	}
+	body, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
 	format := resp.Header.Get("Content-Type")
-	return NewCursor(resp.Body, format)
+	cur, err := NewCursorContext(ctx, body, format)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return cur, nil
 }
 
 // Execute executes a query with a POST and returns if any error occurred. It discards the result.
+// Failed requests are only retried if the RetryPolicy allows retrying non-idempotent requests.
 func (q *Querier) Execute(query interface{}, opts ...QueryOption) error {
+	return q.ExecuteContext(context.Background(), query, opts...)
+}
+
+// ExecuteContext executes a query with a POST and returns if any error
+// occurred. It discards the result. The context will cancel the request.
+func (q *Querier) ExecuteContext(ctx context.Context, query interface{}, opts ...QueryOption) error {
 	opt := q.QueryOptions
 	if len(opts) > 0 {
 		opt = opt.Clone()
@@ -63,22 +92,28 @@ func (q *Querier) Execute(query interface{}, opts ...QueryOption) error {
 		}
 	}
 
-	req, err := q.c.NewQueryRequest(query, opt)
+	req, err := q.c.NewQueryRequestContext(ctx, query, opt)
 	if err != nil {
 		return err
 	}
 
-	resp, err := q.c.Client.Do(req)
+	resp, err := q.c.do(req, false)
 	if err != nil {
 		return err
 	} else if resp.StatusCode/100 != 2 {
 		return ReadError(resp)
 	}
 
+	body, err := responseBody(resp)
+	if err != nil {
+		return err
+	}
 	format := resp.Header.Get("Content-Type")
-	cur, err := NewCursor(resp.Body, format)
+	cur, err := NewCursorContext(ctx, body, format)
 	if err != nil {
+		body.Close()
 		return err
 	}
+	defer cur.Close()
 	return EachResult(cur, func(ResultSet) error { return nil })
 }
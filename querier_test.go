@@ -1,11 +1,13 @@
 package influxdb_test
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	influxdb "github.com/influxdata/influxdb-client"
 )
@@ -72,7 +74,7 @@ func TestQuerier_Select_Param(t *testing.T) {
 	})
 
 	exp := [][]interface{}{
-		[]interface{}{"1970-01-01T00:00:00Z", float64(5)},
+		[]interface{}{"1970-01-01T00:00:00Z", int64(5)},
 	}
 	if !reflect.DeepEqual(got, exp) {
 		t.Fatalf("Values = %q; want %q", got, exp)
@@ -154,7 +156,7 @@ func TestQuerier_Select_Params(t *testing.T) {
 	})
 
 	exp := [][]interface{}{
-		[]interface{}{"1970-01-01T00:00:00Z", float64(5)},
+		[]interface{}{"1970-01-01T00:00:00Z", int64(5)},
 	}
 	if !reflect.DeepEqual(got, exp) {
 		t.Fatalf("Values = %q; want %q", got, exp)
@@ -170,3 +172,54 @@ func TestQuerier_Select_Params(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestQuerier_SelectContext_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, `{"results":[{"series":[{"name":"cpu","columns":["time","mean"],"values":[["1970-01-01T00:00:00Z",5]],"partial":true}],"partial":true}]}`)
+		w.Write([]byte("\n"))
+		w.(http.Flusher).Flush()
+
+		// Hold the connection open until the client goes away.
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	querier := client.Querier()
+	querier.Chunked = true
+	cur, err := querier.SelectContext(ctx, "SELECT mean(value) FROM cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cur.Close()
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := series.NextRow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The next row is blocked waiting on the server. Cancelling the context
+	// should abort the read.
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := series.NextRow(); err != context.Canceled {
+		t.Fatalf("got error %v; want %v", err, context.Canceled)
+	}
+}
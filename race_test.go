@@ -0,0 +1,7 @@
+//go:build race
+
+package influxdb_test
+
+// raceEnabled reports if the tests are running with the race detector, which
+// changes the allocation behavior of the code under test.
+const raceEnabled = true
@@ -0,0 +1,252 @@
+package influxdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for most clients. It retries
+// failed requests up to three times when the server is overloaded or
+// temporarily unreachable.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	MinBackoff:           100 * time.Millisecond,
+	MaxBackoff:           10 * time.Second,
+	Jitter:               0.2,
+	RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	RetryNetworkErrors:   true,
+}
+
+// RetryPolicy configures how a Client retries failed requests. Writes and
+// read-only queries are retried, but queries sent with Execute are only
+// retried if RetryNonIdempotent is set since they may modify the database.
+//
+// MaxAttempts, MinBackoff, MaxBackoff and RetryableStatusCodes fall back to
+// the values from DefaultRetryPolicy when they are unset. Jitter and the
+// boolean fields are used as they are, so a zero RetryPolicy does not retry
+// network errors even though DefaultRetryPolicy does.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is attempted,
+	// including the first attempt. If this is zero, the value from
+	// DefaultRetryPolicy is used.
+	MaxAttempts int
+
+	// MinBackoff is the time to wait before the first retry. The wait time
+	// doubles after every attempt. If this is zero, the value from
+	// DefaultRetryPolicy is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between attempts. A longer
+	// Retry-After sent by the server is limited to this. If this is zero,
+	// the value from DefaultRetryPolicy is used.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of the backoff that is randomized. A jitter of
+	// 0.2 waits between 80% and 100% of the computed backoff. If this is
+	// zero, the backoff is not randomized.
+	Jitter float64
+
+	// RetryableStatusCodes are the HTTP status codes that will be retried. If
+	// this is nil, the value from DefaultRetryPolicy is used.
+	RetryableStatusCodes []int
+
+	// RetryNetworkErrors causes requests to be retried when the request
+	// could not be sent or the response could not be read. Unlike the
+	// fields above, false is not replaced by the value from
+	// DefaultRetryPolicy and disables retrying network errors.
+	RetryNetworkErrors bool
+
+	// RetryNonIdempotent causes queries sent with Execute to be retried.
+	RetryNonIdempotent bool
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return DefaultRetryPolicy.MaxAttempts
+}
+
+// retryable returns true if the response or error from an attempt should be
+// retried.
+func (p *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return p.RetryNetworkErrors
+	}
+	return p.retryableStatus(resp.StatusCode)
+}
+
+// retryableStatus returns true if the status code is one of the retryable
+// status codes.
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	codes := p.RetryableStatusCodes
+	if codes == nil {
+		codes = DefaultRetryPolicy.RetryableStatusCodes
+	}
+	for _, c := range codes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryable returns true if the error returned by a request is one that
+// this policy retries. An ErrHTTP is retryable if its status code is one of
+// the RetryableStatusCodes and a network error is retryable if
+// RetryNetworkErrors is set. Context errors are never retryable.
+func (p *RetryPolicy) IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var httpErr ErrHTTP
+	if errors.As(err, &httpErr) {
+		return p.retryableStatus(httpErr.StatusCode)
+	}
+
+	var netErr net.Error
+	return p.RetryNetworkErrors && errors.As(err, &netErr)
+}
+
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return DefaultRetryPolicy.MaxBackoff
+}
+
+// backoff returns the amount of time to wait after the given attempt. If the
+// server sent a Retry-After header, that is used instead up to MaxBackoff.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return p.retryAfter(d)
+		}
+	}
+
+	min, max := p.MinBackoff, p.maxBackoff()
+	if min <= 0 {
+		min = DefaultRetryPolicy.MinBackoff
+	}
+
+	d := min
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		d -= time.Duration(rand.Float64() * jitter * float64(d))
+	}
+	return d
+}
+
+// errorBackoff returns the amount of time to wait after an attempt that
+// failed with the error. If the error is an ErrHTTP with a RetryAfter, that is
+// used instead up to MaxBackoff.
+func (p *RetryPolicy) errorBackoff(attempt int, err error) time.Duration {
+	var httpErr ErrHTTP
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		return p.retryAfter(httpErr.RetryAfter)
+	}
+	return p.backoff(attempt, nil)
+}
+
+// retryAfter limits the wait requested by the server to MaxBackoff so a
+// server cannot stall the client for an arbitrary amount of time.
+func (p *RetryPolicy) retryAfter(d time.Duration) time.Duration {
+	if max := p.maxBackoff(); d > max {
+		return max
+	}
+	return d
+}
+
+// parseRetryAfter parses the value of a Retry-After header. The header may
+// either be a number of seconds or an HTTP date.
+func parseRetryAfter(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(s); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(s); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// do sends the HTTP request and retries it according to the RetryPolicy. If
+// idempotent is false, the request is only retried if the policy allows
+// retrying non-idempotent requests.
+func (c *Client) do(req *http.Request, idempotent bool) (*http.Response, error) {
+	p := c.RetryPolicy
+	if p == nil || (!idempotent && !p.RetryNonIdempotent) {
+		return c.send(req, idempotent)
+	}
+
+	// A request with a body can only be retried if we can retrieve a fresh
+	// copy of the body.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return c.send(req, idempotent)
+	}
+
+	ctx := req.Context()
+	attempts := p.maxAttempts()
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 {
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := c.send(r, idempotent)
+		if attempt >= attempts || ctx.Err() != nil || !p.retryable(resp, err) {
+			return resp, err
+		}
+
+		wait := p.backoff(attempt, resp)
+		if resp != nil {
+			// Drain the body so the connection can be reused.
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package influxdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func newRetryClient(t *testing.T, url string) *influxdb.Client {
+	client, err := influxdb.NewClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RetryPolicy = &influxdb.RetryPolicy{
+		MaxAttempts:        3,
+		MinBackoff:         time.Millisecond,
+		MaxBackoff:         10 * time.Millisecond,
+		RetryNetworkErrors: true,
+	}
+	return client
+}
+
+func TestClient_Retry_Write(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		if got, want := string(data), "cpu value=5\n"; got != want {
+			t.Errorf("body = %q; want %q", got, want)
+		}
+
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	writer := newRetryClient(t, server.URL).Writer()
+	if _, err := writer.Write([]byte("cpu value=5\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt32(&attempts), int32(3); got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	}
+}
+
+func TestClient_Retry_MaxAttempts(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	writer := newRetryClient(t, server.URL).Writer()
+	if _, err := writer.Write([]byte("cpu value=5\n")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got, want := atomic.LoadInt32(&attempts), int32(3); got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	}
+}
+
+func TestClient_Retry_RetryAfterLimit(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The server asks for an hour, but the wait is limited to MaxBackoff.
+	writer := newRetryClient(t, server.URL).Writer()
+	done := make(chan error, 1)
+	go func() {
+		_, err := writer.Write([]byte("cpu value=5\n"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Retry-After was not limited to MaxBackoff")
+	}
+}
+
+func TestClient_Retry_NotRetryable(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	writer := newRetryClient(t, server.URL).Writer()
+	if _, err := writer.Write([]byte("cpu value=5\n")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got, want := atomic.LoadInt32(&attempts), int32(1); got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	}
+}
+
+func TestClient_Retry_Select(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("q"), "SELECT mean(value) FROM cpu"; got != want {
+			t.Errorf("q = %q; want %q", got, want)
+		}
+
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, `{"results":[{}]}`)
+	}))
+	defer server.Close()
+
+	cur, err := newRetryClient(t, server.URL).Select("SELECT mean(value) FROM cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur.Close()
+
+	if got, want := atomic.LoadInt32(&attempts), int32(2); got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	}
+}
+
+func TestClient_Retry_Execute(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, `{"results":[{}]}`)
+	}))
+	defer server.Close()
+
+	// Execute must not be retried by default.
+	client := newRetryClient(t, server.URL)
+	if err := client.Execute("CREATE DATABASE db0"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got, want := atomic.LoadInt32(&attempts), int32(1); got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	}
+
+	// Opting in retries the statement.
+	atomic.StoreInt32(&attempts, 0)
+	client.RetryPolicy.RetryNonIdempotent = true
+	if err := client.Execute("CREATE DATABASE db0"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := atomic.LoadInt32(&attempts), int32(2); got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	}
+}
@@ -0,0 +1,318 @@
+package influxdb
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Scan copies the values from the Series and Row into the struct pointed to
+// by dst. The struct fields are mapped using the influx struct tag:
+//
+//	Measurement string    `influx:"measurement"`
+//	Host        string    `influx:"tag,host"`
+//	Value       float64   `influx:"column,value"`
+//	Time        time.Time `influx:"time"`
+//
+// The first element of the tag is the kind of value to copy and the second
+// element is the name of the tag or column. If the name is omitted, the name
+// of the struct field is used. Fields without an influx tag are ignored and
+// embedded structs without a tag have their fields promoted.
+//
+// Values are converted to the type of the struct field. Numbers may be scanned
+// into any numeric type that can hold the value, strings may be scanned into
+// numeric and boolean fields if they can be parsed, and a time may be scanned
+// from a time.Time, an RFC3339 string, or the nanoseconds since the epoch.
+// If a value cannot be converted, an ErrScan is returned.
+func Scan(series Series, row Row, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination must be a non-nil pointer to a struct, got %T", dst)
+	}
+	return scanStruct(series, row, rv.Elem())
+}
+
+// ScanAll reads every remaining row from the Series and appends them to the
+// slice pointed to by dst. The slice may contain structs or pointers to
+// structs. See Scan for how the values are mapped to the struct.
+func ScanAll(series Series, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan destination must be a non-nil pointer to a slice, got %T", dst)
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("scan destination must be a slice of structs, got %T", dst)
+	}
+
+	return EachRow(series, func(row Row) error {
+		elem := reflect.New(elemType)
+		if err := scanStruct(series, row, elem.Elem()); err != nil {
+			return err
+		}
+
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+		return nil
+	})
+}
+
+func scanStruct(series Series, row Row, v reflect.Value) error {
+	info := getStructInfo(v.Type())
+	for _, f := range info.fields {
+		var src interface{}
+		switch f.kind {
+		case fieldKindMeasurement:
+			src = series.Name()
+		case fieldKindTag:
+			value, ok := lookupTag(series.Tags(), f.name)
+			if !ok {
+				continue
+			}
+			src = value
+		case fieldKindField:
+			src = row.ValueByName(f.name)
+		case fieldKindTime:
+			t := row.Time()
+			if t.IsZero() {
+				continue
+			}
+			src = t
+		}
+
+		dst, err := fieldByIndex(v, f.index)
+		if err == nil {
+			err = setValue(dst, src)
+		}
+		if err != nil {
+			column := f.name
+			if f.kind == fieldKindTime {
+				column = "time"
+			}
+			return ErrScan{
+				Field:  f.goName,
+				Column: column,
+				Value:  src,
+				Type:   dst.Type(),
+				Err:    err,
+			}
+		}
+	}
+	return nil
+}
+
+// lookupTag finds the tag with the given key.
+func lookupTag(tags Tags, key string) (string, bool) {
+	for _, t := range tags {
+		if t.Key == key {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// fieldByIndex returns the nested field and allocates any nil embedded
+// pointers along the way. If a nil embedded pointer cannot be set, the
+// pointer is returned along with an error.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, errUnexportedEmbed
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+var (
+	errTypeMismatch = errors.New("type mismatch")
+	errOverflow     = errors.New("value out of range")
+
+	// errUnexportedEmbed is returned when a nil pointer to an unexported
+	// embedded struct would need to be allocated. Reflection cannot set it.
+	errUnexportedEmbed = errors.New("cannot set embedded pointer to unexported struct")
+)
+
+// setValue converts src into the type of dst and stores it.
+func setValue(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return setValue(dst.Elem(), src)
+	}
+
+	sv := reflect.ValueOf(src)
+	if dst.Kind() == reflect.Interface {
+		if !sv.Type().AssignableTo(dst.Type()) {
+			return errTypeMismatch
+		}
+		dst.Set(sv)
+		return nil
+	}
+
+	if dst.Type() == timeType {
+		t, err := convertTime(src)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	// Allow types to parse their own representation from a string.
+	if s, ok := src.(string); ok && dst.CanAddr() {
+		if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return errTypeMismatch
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		switch src := src.(type) {
+		case bool:
+			dst.SetBool(src)
+		case string:
+			b, err := strconv.ParseBool(src)
+			if err != nil {
+				return err
+			}
+			dst.SetBool(b)
+		default:
+			return errTypeMismatch
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch src := src.(type) {
+		case int64:
+			n = src
+		case time.Time:
+			n = src.UnixNano()
+		case uint64:
+			if src > math.MaxInt64 {
+				return errOverflow
+			}
+			n = int64(src)
+		case float64:
+			if src != math.Trunc(src) || src < math.MinInt64 || src >= math.MaxInt64 {
+				return errTypeMismatch
+			}
+			n = int64(src)
+		case string:
+			v, err := strconv.ParseInt(src, 10, 64)
+			if err != nil {
+				return err
+			}
+			n = v
+		default:
+			return errTypeMismatch
+		}
+		if dst.OverflowInt(n) {
+			return errOverflow
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch src := src.(type) {
+		case int64:
+			if src < 0 {
+				return errOverflow
+			}
+			n = uint64(src)
+		case uint64:
+			n = src
+		case float64:
+			if src != math.Trunc(src) || src < 0 || src >= math.MaxUint64 {
+				return errTypeMismatch
+			}
+			n = uint64(src)
+		case string:
+			v, err := strconv.ParseUint(src, 10, 64)
+			if err != nil {
+				return err
+			}
+			n = v
+		default:
+			return errTypeMismatch
+		}
+		if dst.OverflowUint(n) {
+			return errOverflow
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch src := src.(type) {
+		case float64:
+			f = src
+		case int64:
+			f = float64(src)
+		case uint64:
+			f = float64(src)
+		case string:
+			v, err := strconv.ParseFloat(src, 64)
+			if err != nil {
+				return err
+			}
+			f = v
+		default:
+			return errTypeMismatch
+		}
+		if dst.OverflowFloat(f) {
+			return errOverflow
+		}
+		dst.SetFloat(f)
+	default:
+		if !sv.Type().ConvertibleTo(dst.Type()) || sv.Kind() != dst.Kind() {
+			return errTypeMismatch
+		}
+		dst.Set(sv.Convert(dst.Type()))
+	}
+	return nil
+}
+
+// convertTime converts a time value returned by a cursor into a time.Time.
+func convertTime(src interface{}) (time.Time, error) {
+	switch src := src.(type) {
+	case time.Time:
+		return src, nil
+	case int64:
+		return time.Unix(0, src).UTC(), nil
+	case float64:
+		return time.Unix(0, int64(src)).UTC(), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, src)
+	}
+	return time.Time{}, errTypeMismatch
+}
@@ -0,0 +1,216 @@
+package influxdb_test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+// nextSeries returns the first series from the JSON output.
+func nextSeries(t *testing.T, output string) influxdb.Series {
+	cur, err := influxdb.NewCursor(ioutil.NopCloser(strings.NewReader(output)), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cur.NextSet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	series, err := result.NextSeries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return series
+}
+
+type cpuBase struct {
+	Measurement string `influx:"measurement"`
+	Host        string `influx:"tag,host"`
+}
+
+type cpu struct {
+	cpuBase
+	Time   time.Time `influx:"time"`
+	Value  float64   `influx:"column,value"`
+	Count  int32     `influx:"column,count"`
+	Status *string   `influx:"column,status"`
+	Ignore string
+}
+
+func TestScan(t *testing.T) {
+	series := nextSeries(t, `{"results":[{"series":[{"name":"cpu","tags":{"host":"server01"},"columns":["time","value","count","status"],"values":[["2010-01-01T00:00:00Z",2,5,"ok"],["2010-01-01T00:00:10Z",2.5,6,null]]}]}]}`)
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got cpu
+	if err := influxdb.Scan(series, row, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	status := "ok"
+	want := cpu{
+		cpuBase: cpuBase{Measurement: "cpu", Host: "server01"},
+		Time:    mustParseTime("2010-01-01T00:00:00Z"),
+		Value:   2,
+		Count:   5,
+		Status:  &status,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+
+	var all []*cpu
+	if err := influxdb.ScanAll(series, &all); err != nil {
+		t.Fatal(err)
+	}
+
+	want = cpu{
+		cpuBase: cpuBase{Measurement: "cpu", Host: "server01"},
+		Time:    mustParseTime("2010-01-01T00:00:10Z"),
+		Value:   2.5,
+		Count:   6,
+	}
+	if len(all) != 1 {
+		t.Fatalf("got %d rows; want 1", len(all))
+	} else if !reflect.DeepEqual(*all[0], want) {
+		t.Fatalf("got %#v; want %#v", *all[0], want)
+	}
+}
+
+func TestScan_Conversions(t *testing.T) {
+	type conversions struct {
+		Time  int64   `influx:"time"`
+		Zone  int     `influx:"tag,zone"`
+		Up    bool    `influx:"tag,up"`
+		Total uint64  `influx:"column,total"`
+		Ratio float32 `influx:"column,ratio"`
+	}
+
+	series := nextSeries(t, `{"results":[{"series":[{"name":"cpu","tags":{"zone":"3","up":"true"},"columns":["time","total","ratio"],"values":[[1262304000000000000,9223372036854775808,1]]}]}]}`)
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got conversions
+	if err := influxdb.Scan(series, row, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := conversions{
+		Time:  1262304000000000000,
+		Zone:  3,
+		Up:    true,
+		Total: 9223372036854775808,
+		Ratio: 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v; want %#v", got, want)
+	}
+}
+
+func TestScan_Mismatch(t *testing.T) {
+	series := nextSeries(t, `{"results":[{"series":[{"name":"cpu","columns":["time","value","count"],"values":[["2010-01-01T00:00:00Z","high",300]]}]}]}`)
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var v struct {
+		Value float64 `influx:"column,value"`
+	}
+	if err := influxdb.Scan(series, row, &v); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(influxdb.ErrScan); !ok {
+		t.Fatalf("got error type %T; want %T", err, e)
+	} else if e.Field != "Value" || e.Column != "value" || e.Value != "high" {
+		t.Fatalf("unexpected error: %#v", e)
+	}
+
+	var overflow struct {
+		Count int8 `influx:"column,count"`
+	}
+	if err := influxdb.Scan(series, row, &overflow); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(influxdb.ErrScan); !ok {
+		t.Fatalf("got error type %T; want %T", err, influxdb.ErrScan{})
+	}
+
+	if err := influxdb.Scan(series, row, v); err == nil {
+		t.Fatal("expected error for a non-pointer destination")
+	}
+}
+
+func TestScanAll_UnexportedEmbeddedPointer(t *testing.T) {
+	series := nextSeries(t, `{"results":[{"series":[{"name":"cpu","tags":{"host":"server01"},"columns":["time","value"],"values":[["2010-01-01T00:00:00Z",5]]}]}]}`)
+
+	var rows []struct {
+		*hostTags
+		Value float64 `influx:"column,value"`
+	}
+	if err := influxdb.ScanAll(series, &rows); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(influxdb.ErrScan); !ok {
+		t.Fatalf("got error type %T; want %T", err, e)
+	} else if e.Field != "Host" || e.Column != "host" {
+		t.Fatalf("unexpected error: %#v", e)
+	}
+}
+
+type recursive struct {
+	*recursive
+	Value float64 `influx:"column,value"`
+}
+
+func TestScan_RecursiveEmbed(t *testing.T) {
+	series := nextSeries(t, `{"results":[{"series":[{"name":"cpu","columns":["time","value"],"values":[["2010-01-01T00:00:00Z",5]]}]}]}`)
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var v recursive
+	if err := influxdb.Scan(series, row, &v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got, want := v.Value, float64(5); got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	} else if v.recursive != nil {
+		t.Fatal("expected the embedded pointer to be left nil")
+	}
+}
+
+// temperature is an unexported type so it cannot be set when it is embedded.
+type temperature float64
+
+func TestScan_UnexportedEmbeddedField(t *testing.T) {
+	series := nextSeries(t, `{"results":[{"series":[{"name":"cpu","columns":["time","temp","value"],"values":[["2010-01-01T00:00:00Z",30,5]]}]}]}`)
+
+	row, err := series.NextRow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var v struct {
+		temperature `influx:"field,temp"`
+		Value       float64 `influx:"field,value"`
+	}
+	if err := influxdb.Scan(series, row, &v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got, want := v.Value, float64(5); got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	} else if v.temperature != 0 {
+		t.Fatal("expected the unexported embedded field to be skipped")
+	}
+}
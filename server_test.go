@@ -0,0 +1,102 @@
+package influxdb_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+// testServer records the body of every write it accepts and answers queries
+// with a single series. It responds with 503 Service Unavailable while it is
+// down.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	down     bool
+	requests int
+	failed   int
+	writes   []string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.down {
+			s.failed++
+			http.Error(w, "expected error", http.StatusServiceUnavailable)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/write":
+			s.writes = append(s.writes, string(data))
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"results":[{"series":[{"name":"cpu","columns":["time","value"],"values":[[0,5]]}]}]}`+"\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	return s
+}
+
+func (s *testServer) SetDown(down bool) {
+	s.mu.Lock()
+	s.down = down
+	s.mu.Unlock()
+}
+
+// Requests returns the number of requests the server has received.
+func (s *testServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Failed returns the number of requests that failed while the server was down.
+func (s *testServer) Failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
+// Writes returns the body of every write the server accepted.
+func (s *testServer) Writes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.writes...)
+}
+
+// Lines returns every line written to the server.
+func (s *testServer) Lines() []string {
+	var lines []string
+	for _, data := range s.Writes() {
+		lines = append(lines, strings.Split(strings.TrimSuffix(data, "\n"), "\n")...)
+	}
+	return lines
+}
+
+// newTestWriter returns a Writer that writes to the db0 database of the server.
+func newTestWriter(t *testing.T, url string) *influxdb.Writer {
+	client, err := influxdb.NewClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.Database = "db0"
+	return writer
+}
@@ -0,0 +1,763 @@
+package influxdb
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSpoolSegmentSize is the default maximum size of a segment file
+	// used by a SpoolWriter.
+	DefaultSpoolSegmentSize = 16 << 20
+
+	// DefaultSpoolMinBackoff is the default time a SpoolWriter waits before
+	// retrying a failed write.
+	DefaultSpoolMinBackoff = time.Second
+
+	// DefaultSpoolMaxBackoff is the default maximum time a SpoolWriter waits
+	// between retries of a failed write.
+	DefaultSpoolMaxBackoff = time.Minute
+)
+
+// SpoolPolicy decides what a SpoolWriter does when it is full.
+type SpoolPolicy string
+
+const (
+	// SpoolReject rejects new writes with ErrSpoolFull when the spool is full.
+	SpoolReject = SpoolPolicy("reject")
+
+	// SpoolDropOldest deletes the oldest segments until there is room for
+	// the new write.
+	SpoolDropOldest = SpoolPolicy("drop-oldest")
+)
+
+func (p SpoolPolicy) String() string {
+	return string(p)
+}
+
+// SpoolOptions is a set of configuration options for configuring a SpoolWriter.
+type SpoolOptions struct {
+	// Dir is the directory where the segment files are stored. It is created
+	// if it does not exist. Only one SpoolWriter may use a directory at a time.
+	Dir string
+
+	// SegmentSize is the maximum size of a segment file in bytes. Space is
+	// reclaimed and points are dropped one segment at a time, so this should
+	// be much smaller than MaxSize. If this is zero, DefaultSpoolSegmentSize
+	// is used.
+	SegmentSize int64
+
+	// MaxSize is the maximum number of bytes stored on disk. If this is zero,
+	// the size of the spool is not limited.
+	MaxSize int64
+
+	// Policy decides what happens when a write would exceed MaxSize. If this
+	// is empty, SpoolReject is used.
+	Policy SpoolPolicy
+
+	// SyncWrites syncs the segment file to disk after every write. Without
+	// this, the most recent writes may be lost if the machine crashes.
+	SyncWrites bool
+
+	// MinBackoff is the time to wait before retrying a failed write. The
+	// wait time doubles after every failure. If this is zero,
+	// DefaultSpoolMinBackoff is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between retries. A longer
+	// Retry-After sent by the server is limited to this. If this is zero,
+	// DefaultSpoolMaxBackoff is used.
+	MaxBackoff time.Duration
+
+	// RetryPolicy decides which failed writes are kept and retried. Writes
+	// that fail with an error the policy does not retry are discarded. Only
+	// the retryable errors are taken from the policy, since spooled writes
+	// are retried until they succeed using MinBackoff and MaxBackoff. If
+	// this is nil, DefaultRetryPolicy is used.
+	RetryPolicy *RetryPolicy
+
+	// ErrorHandler is called with any error that happens while writing the
+	// spooled points to the server in the background.
+	ErrorHandler func(err error)
+}
+
+// SpoolStats holds metrics on the state of a SpoolWriter.
+type SpoolStats struct {
+	// Segments is the number of segment files on disk.
+	Segments int
+
+	// Records is the number of writes waiting to be sent to the server.
+	Records int64
+
+	// Bytes is the number of bytes waiting to be sent to the server.
+	Bytes int64
+
+	// Written is the number of writes sent to the server since the
+	// SpoolWriter was opened.
+	Written int64
+
+	// Failed is the number of writes the server rejected with an error that
+	// cannot be retried. These writes are discarded.
+	Failed int64
+
+	// Dropped is the number of writes deleted by the SpoolDropOldest policy
+	// or because they were corrupted on disk.
+	Dropped int64
+
+	// Rejected is the number of writes rejected because the spool was full.
+	Rejected int64
+}
+
+// SpoolWriter durably queues points on disk and writes them to the server in
+// the background. If the server is unavailable, the points stay on disk and
+// are retried with a backoff until they are written, even if the process is
+// restarted. It is safe to write points from multiple goroutines.
+//
+// The points are encoded with the options of the Writer when they are added
+// to the spool, so the Writer should use the same Precision and Protocol each
+// time the spool is opened.
+type SpoolWriter struct {
+	w   *Writer
+	opt SpoolOptions
+
+	mu       sync.Mutex
+	segments []*spoolSegment
+	wf       *os.File
+	rf       *os.File
+	rfID     uint64
+	readOff  int64
+	readRecs int64
+	stats    SpoolStats
+	closed   bool
+
+	// gen is incremented whenever unread records are removed so a write in
+	// progress knows not to advance the read position.
+	gen uint64
+
+	// empty is closed when the spool has no more records to write.
+	empty chan struct{}
+
+	notify  chan struct{}
+	ctx     context.Context
+	cancel  func()
+	stopped chan struct{}
+}
+
+// spoolSegment is a segment file containing records.
+type spoolSegment struct {
+	id      uint64
+	size    int64
+	records int64
+}
+
+// spoolBatch is a set of records read from a segment.
+type spoolBatch struct {
+	gen     uint64
+	id      uint64
+	end     int64
+	records int64
+	data    []byte
+}
+
+// spoolHeaderSize is the size of the header for each record. The header is
+// the length of the record followed by its CRC-32 checksum.
+const spoolHeaderSize = 8
+
+// NewSpoolWriter opens the spool in the directory and starts writing any
+// points stored in it with the Writer. Close must be called to release the
+// files and the background goroutine.
+func NewSpoolWriter(w *Writer, opt SpoolOptions) (*SpoolWriter, error) {
+	if opt.Dir == "" {
+		return nil, fmt.Errorf("spool directory is required")
+	}
+	if opt.SegmentSize <= 0 {
+		opt.SegmentSize = DefaultSpoolSegmentSize
+	}
+	if opt.Policy == "" {
+		opt.Policy = SpoolReject
+	} else if opt.Policy != SpoolReject && opt.Policy != SpoolDropOldest {
+		return nil, fmt.Errorf("unknown spool policy: %s", opt.Policy)
+	}
+	if opt.MinBackoff <= 0 {
+		opt.MinBackoff = DefaultSpoolMinBackoff
+	}
+	if opt.MaxBackoff <= 0 {
+		opt.MaxBackoff = DefaultSpoolMaxBackoff
+	}
+
+	if err := os.MkdirAll(opt.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &SpoolWriter{
+		w:       w,
+		opt:     opt,
+		notify:  make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+	if err := s.open(); err != nil {
+		s.closeFiles()
+		return nil, err
+	}
+	if s.pending() > 0 {
+		s.empty = make(chan struct{})
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run()
+	return s, nil
+}
+
+// Write adds the line protocol to the spool. The data must contain complete
+// points. It returns the number of bytes added.
+func (s *SpoolWriter) Write(data []byte) (n int, err error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	record := data
+	if data[len(data)-1] != '\n' {
+		record = append(append(make([]byte, 0, len(data)+1), data...), '\n')
+	}
+	if err := s.append(record); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// WritePoint encodes the point and adds it to the spool.
+func (s *SpoolWriter) WritePoint(pt Point) error {
+	return s.WriteBatch([]Point{pt})
+}
+
+// WriteBatch encodes the points and adds them to the spool as a single
+// record. If any point fails to validate or encode, none of the points are
+// added.
+func (s *SpoolWriter) WriteBatch(pts []Point) error {
+	data, err := s.w.encodeBatch(pts, nil)
+	if err != nil {
+		return err
+	} else if len(data) == 0 {
+		return nil
+	}
+	return s.append(data)
+}
+
+// Stats returns the current metrics for the spool.
+func (s *SpoolWriter) Stats() SpoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Segments = len(s.segments)
+	stats.Records = s.pending()
+	stats.Bytes = s.diskSize() - s.readOff
+	return stats
+}
+
+// Drain waits until every point in the spool has been written to the server
+// or the context is done.
+func (s *SpoolWriter) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	empty := s.empty
+	s.mu.Unlock()
+	if empty == nil {
+		return nil
+	}
+
+	select {
+	case <-empty:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops writing points to the server and closes the segment files. Any
+// points that have not been written remain on disk and are written when the
+// spool is opened again. Any writes after Close is called will return
+// ErrWriterClosed.
+func (s *SpoolWriter) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrWriterClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.cancel()
+	<-s.stopped
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeFiles()
+}
+
+// open recovers the state of the spool from the files in the directory.
+func (s *SpoolWriter) open() error {
+	entries, err := ioutil.ReadDir(s.opt.Dir)
+	if err != nil {
+		return err
+	}
+
+	var ids []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".seg") {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, ".seg"), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	posID, posOff, ok := s.readPosition()
+	if !ok && len(ids) > 0 {
+		posID, posOff = ids[0], 0
+	}
+
+	for _, id := range ids {
+		if id < posID {
+			// This segment was completely written before the last shutdown.
+			if err := os.Remove(s.segmentPath(id)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		seg, boundary, read, err := s.recoverSegment(id, posOff, id == posID)
+		if err != nil {
+			return err
+		}
+		if id == posID {
+			s.readOff, s.readRecs = boundary, read
+		}
+		s.segments = append(s.segments, seg)
+	}
+
+	if len(s.segments) == 0 {
+		if posID == 0 {
+			posID = 1
+		}
+		s.segments = append(s.segments, &spoolSegment{id: posID})
+		s.readOff, s.readRecs = 0, 0
+	}
+
+	active := s.segments[len(s.segments)-1]
+	s.wf, err = os.OpenFile(s.segmentPath(active.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+// recoverSegment scans the records in the segment file and truncates any
+// partially written or corrupted records at the end of the file. If the
+// segment is the head of the queue, the offset of the last record boundary
+// before the read position and the number of records before it are also
+// returned.
+func (s *SpoolWriter) recoverSegment(id uint64, posOff int64, head bool) (*spoolSegment, int64, int64, error) {
+	path := s.segmentPath(id)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	seg := &spoolSegment{id: id}
+	var boundary, read int64
+	for seg.size < int64(len(data)) {
+		payload, ok := decodeSpoolRecord(data[seg.size:])
+		if !ok {
+			// The rest of the file was not completely written or is
+			// corrupt. Remove it so new records are appended after the
+			// last valid record.
+			s.stats.Dropped++
+			if err := os.Truncate(path, seg.size); err != nil {
+				return nil, 0, 0, err
+			}
+			break
+		}
+
+		end := seg.size + spoolHeaderSize + int64(len(payload))
+		if head && end <= posOff {
+			boundary, read = end, read+1
+		}
+		seg.size = end
+		seg.records++
+	}
+	return seg, boundary, read, nil
+}
+
+// decodeSpoolRecord decodes the record at the start of the buffer. It returns
+// false if the record is incomplete or its checksum does not match.
+func decodeSpoolRecord(b []byte) ([]byte, bool) {
+	if len(b) < spoolHeaderSize {
+		return nil, false
+	}
+	n := int64(binary.BigEndian.Uint32(b[0:4]))
+	sum := binary.BigEndian.Uint32(b[4:8])
+	if int64(len(b)-spoolHeaderSize) < n {
+		return nil, false
+	}
+	payload := b[spoolHeaderSize : spoolHeaderSize+n]
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, false
+	}
+	return payload, true
+}
+
+// append adds a record to the active segment.
+func (s *SpoolWriter) append(data []byte) error {
+	size := int64(spoolHeaderSize + len(data))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrWriterClosed
+	}
+
+	if s.opt.MaxSize > 0 {
+		if size > s.opt.MaxSize {
+			s.stats.Rejected++
+			return ErrSpoolFull
+		}
+		for s.diskSize()+size > s.opt.MaxSize {
+			if s.opt.Policy != SpoolDropOldest {
+				s.stats.Rejected++
+				return ErrSpoolFull
+			}
+			if err := s.dropOldest(); err != nil {
+				return err
+			}
+		}
+	}
+
+	active := s.segments[len(s.segments)-1]
+	if active.size > 0 && active.size+size > s.opt.SegmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		active = s.segments[len(s.segments)-1]
+	}
+
+	record := make([]byte, size)
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(data))
+	copy(record[spoolHeaderSize:], data)
+
+	if _, err := s.wf.Write(record); err != nil {
+		// Remove anything that was partially written so the next record
+		// starts at a record boundary.
+		s.wf.Truncate(active.size)
+		return err
+	}
+	if s.opt.SyncWrites {
+		if err := s.wf.Sync(); err != nil {
+			return err
+		}
+	}
+	active.size += size
+	active.records++
+
+	if s.empty == nil {
+		s.empty = make(chan struct{})
+	}
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// rotate starts a new active segment. The lock must be held.
+func (s *SpoolWriter) rotate() error {
+	id := s.segments[len(s.segments)-1].id + 1
+	f, err := os.OpenFile(s.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.wf.Close()
+	s.wf = f
+	s.segments = append(s.segments, &spoolSegment{id: id})
+	return nil
+}
+
+// dropOldest removes the unread records in the oldest segment. The lock must
+// be held.
+func (s *SpoolWriter) dropOldest() error {
+	head := s.segments[0]
+	s.stats.Dropped += head.records - s.readRecs
+	s.readOff, s.readRecs = head.size, head.records
+	s.gen++
+	return s.finishHead()
+}
+
+// finishHead removes the head segment once all of its records have been
+// read. If the head segment is also the active segment, it is truncated
+// instead. The lock must be held.
+func (s *SpoolWriter) finishHead() error {
+	head := s.segments[0]
+	if s.readOff < head.size {
+		return s.writePosition()
+	}
+
+	if len(s.segments) > 1 {
+		if s.rf != nil && s.rfID == head.id {
+			s.rf.Close()
+			s.rf = nil
+		}
+		s.segments = s.segments[1:]
+		s.readOff, s.readRecs = 0, 0
+		if err := s.writePosition(); err != nil {
+			return err
+		}
+		return os.Remove(s.segmentPath(head.id))
+	}
+
+	// Everything has been read so the active segment can be reused.
+	if err := s.wf.Truncate(0); err != nil {
+		return err
+	}
+	head.size, head.records = 0, 0
+	s.readOff, s.readRecs = 0, 0
+	return s.writePosition()
+}
+
+// next reads the next batch of records from the head segment. It returns nil
+// if there are no records to write.
+func (s *SpoolWriter) next() (*spoolBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending() == 0 {
+		return nil, nil
+	}
+
+	head := s.segments[0]
+	if s.rf == nil || s.rfID != head.id {
+		if s.rf != nil {
+			s.rf.Close()
+		}
+		f, err := os.Open(s.segmentPath(head.id))
+		if err != nil {
+			s.rf = nil
+			return nil, err
+		}
+		s.rf, s.rfID = f, head.id
+	}
+
+	limit := s.w.ChunkSize
+	if limit <= 0 {
+		limit = DefaultWriteChunkSize
+	}
+
+	b := &spoolBatch{gen: s.gen, id: head.id, end: s.readOff}
+	var hdr [spoolHeaderSize]byte
+	for b.end < head.size && (len(b.data) == 0 || len(b.data) < limit) {
+		if _, err := s.rf.ReadAt(hdr[:], b.end); err != nil {
+			return nil, s.corrupt(head, err)
+		}
+		n := int64(binary.BigEndian.Uint32(hdr[0:4]))
+		if b.end+spoolHeaderSize+n > head.size {
+			return nil, s.corrupt(head, io.ErrUnexpectedEOF)
+		}
+		if len(b.data) > 0 && len(b.data)+int(n) > limit {
+			break
+		}
+
+		start := len(b.data)
+		b.data = append(b.data, make([]byte, n)...)
+		if _, err := s.rf.ReadAt(b.data[start:], b.end+spoolHeaderSize); err != nil {
+			return nil, s.corrupt(head, err)
+		}
+		if crc32.ChecksumIEEE(b.data[start:]) != binary.BigEndian.Uint32(hdr[4:8]) {
+			return nil, s.corrupt(head, fmt.Errorf("checksum mismatch at offset %d", b.end))
+		}
+		b.end += spoolHeaderSize + n
+		b.records++
+	}
+	return b, nil
+}
+
+// corrupt discards the unread records in the head segment because they
+// cannot be read. The lock must be held.
+func (s *SpoolWriter) corrupt(head *spoolSegment, err error) error {
+	err = fmt.Errorf("spool segment %d is corrupt: %s", head.id, err)
+	if derr := s.dropOldest(); derr != nil {
+		return derr
+	}
+	s.checkEmpty()
+	return err
+}
+
+// advance moves the read position past the batch once it has been written.
+func (s *SpoolWriter) advance(b *spoolBatch, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b.gen != s.gen || s.closed {
+		// The records were dropped while they were being written.
+		return nil
+	}
+	s.readOff = b.end
+	s.readRecs += b.records
+	if err == nil {
+		s.stats.Written += b.records
+	} else {
+		s.stats.Failed += b.records
+	}
+
+	defer s.checkEmpty()
+	return s.finishHead()
+}
+
+// checkEmpty closes the empty channel if there are no records left to write.
+// The lock must be held.
+func (s *SpoolWriter) checkEmpty() {
+	if s.empty != nil && s.pending() == 0 {
+		close(s.empty)
+		s.empty = nil
+	}
+}
+
+// run is the background goroutine that writes the records to the server.
+func (s *SpoolWriter) run() {
+	defer close(s.stopped)
+
+	retry := s.retryPolicy()
+	policy := RetryPolicy{
+		MinBackoff: s.opt.MinBackoff,
+		MaxBackoff: s.opt.MaxBackoff,
+		Jitter:     DefaultRetryPolicy.Jitter,
+	}
+
+	attempt := 0
+	for {
+		b, err := s.next()
+		if b == nil && err == nil {
+			select {
+			case <-s.notify:
+				continue
+			case <-s.ctx.Done():
+				return
+			}
+		}
+
+		if err == nil {
+			_, err = s.w.WriteContext(s.ctx, b.data)
+			if s.ctx.Err() != nil {
+				return
+			}
+		}
+		if err != nil && (b == nil || retry.IsRetryable(err)) {
+			attempt++
+			s.handleError(err)
+
+			timer := time.NewTimer(policy.errorBackoff(attempt, err))
+			select {
+			case <-timer.C:
+				continue
+			case <-s.ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+
+		// Any other error means the server will never accept the records
+		// so they are discarded.
+		attempt = 0
+		if err != nil {
+			s.handleError(err)
+		}
+		if err := s.advance(b, err); err != nil {
+			s.handleError(err)
+		}
+	}
+}
+
+// retryPolicy returns the policy that decides which failed writes are retried.
+func (s *SpoolWriter) retryPolicy() *RetryPolicy {
+	if s.opt.RetryPolicy != nil {
+		return s.opt.RetryPolicy
+	}
+	return &DefaultRetryPolicy
+}
+
+func (s *SpoolWriter) handleError(err error) {
+	if s.opt.ErrorHandler != nil {
+		s.opt.ErrorHandler(err)
+	}
+}
+
+// pending returns the number of records that have not been written. The lock
+// must be held.
+func (s *SpoolWriter) pending() int64 {
+	var n int64
+	for _, seg := range s.segments {
+		n += seg.records
+	}
+	return n - s.readRecs
+}
+
+// diskSize returns the size of all of the segments. The lock must be held.
+func (s *SpoolWriter) diskSize() int64 {
+	var n int64
+	for _, seg := range s.segments {
+		n += seg.size
+	}
+	return n
+}
+
+func (s *SpoolWriter) segmentPath(id uint64) string {
+	return filepath.Join(s.opt.Dir, fmt.Sprintf("%020d.seg", id))
+}
+
+// readPosition reads the segment and offset of the next record to write from
+// the position file.
+func (s *SpoolWriter) readPosition() (id uint64, off int64, ok bool) {
+	data, err := ioutil.ReadFile(filepath.Join(s.opt.Dir, "position"))
+	if err != nil {
+		return 0, 0, false
+	}
+	if _, err := fmt.Sscanf(string(data), "%d %d\n", &id, &off); err != nil {
+		return 0, 0, false
+	}
+	return id, off, true
+}
+
+// writePosition atomically replaces the position file with the current read
+// position. The lock must be held.
+func (s *SpoolWriter) writePosition() error {
+	path := filepath.Join(s.opt.Dir, "position")
+	data := fmt.Sprintf("%d %d\n", s.segments[0].id, s.readOff)
+	if err := ioutil.WriteFile(path+".tmp", []byte(data), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// closeFiles closes the open segment files. The lock must be held.
+func (s *SpoolWriter) closeFiles() error {
+	var err error
+	if s.rf != nil {
+		s.rf.Close()
+		s.rf = nil
+	}
+	if s.wf != nil {
+		err = s.wf.Close()
+		s.wf = nil
+	}
+	return err
+}
@@ -0,0 +1,321 @@
+package influxdb_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func newSpoolWriter(t *testing.T, url string, opt influxdb.SpoolOptions) *influxdb.SpoolWriter {
+	if opt.MinBackoff == 0 {
+		opt.MinBackoff = time.Millisecond
+	}
+	if opt.MaxBackoff == 0 {
+		opt.MaxBackoff = 10 * time.Millisecond
+	}
+	w, err := influxdb.NewSpoolWriter(newTestWriter(t, url), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func drain(t *testing.T, w *influxdb.SpoolWriter) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeSpoolPoints(t *testing.T, w *influxdb.SpoolWriter, start, n int) {
+	for i := start; i < start+n; i++ {
+		pt := influxdb.Point{
+			Name:   "cpu",
+			Fields: map[string]interface{}{"value": i},
+		}
+		if err := w.WritePoint(pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSpoolWriter(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	w := newSpoolWriter(t, server.URL, influxdb.SpoolOptions{Dir: t.TempDir()})
+	defer w.Close()
+
+	writeSpoolPoints(t, w, 0, 3)
+	if _, err := w.Write([]byte("mem value=4i")); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, w)
+
+	want := []string{"cpu value=0i", "cpu value=1i", "cpu value=2i", "mem value=4i"}
+	if got := server.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines:\n\ngot=%q\n\nwant=%q", got, want)
+	}
+
+	stats := w.Stats()
+	if got, want := stats.Written, int64(4); got != want {
+		t.Errorf("Written = %d; want %d", got, want)
+	}
+	if got, want := stats.Records, int64(0); got != want {
+		t.Errorf("Records = %d; want %d", got, want)
+	}
+	if got, want := stats.Bytes, int64(0); got != want {
+		t.Errorf("Bytes = %d; want %d", got, want)
+	}
+}
+
+func TestSpoolWriter_Outage(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	server.SetDown(true)
+
+	var (
+		mu   sync.Mutex
+		errs int
+	)
+	w := newSpoolWriter(t, server.URL, influxdb.SpoolOptions{
+		Dir: t.TempDir(),
+		ErrorHandler: func(err error) {
+			mu.Lock()
+			errs++
+			mu.Unlock()
+		},
+	})
+	defer w.Close()
+
+	writeSpoolPoints(t, w, 0, 2)
+	for server.Failed() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if got, want := w.Stats().Records, int64(2); got != want {
+		t.Errorf("Records = %d; want %d", got, want)
+	}
+
+	server.SetDown(false)
+	drain(t, w)
+
+	want := []string{"cpu value=0i", "cpu value=1i"}
+	if got := server.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines:\n\ngot=%q\n\nwant=%q", got, want)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if errs < 3 {
+		t.Errorf("ErrorHandler called %d times; want at least 3", errs)
+	}
+}
+
+func TestSpoolWriter_Recovery(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	server.SetDown(true)
+
+	dir := t.TempDir()
+	w := newSpoolWriter(t, server.URL, influxdb.SpoolOptions{
+		Dir:         dir,
+		SegmentSize: 64,
+	})
+	writeSpoolPoints(t, w, 0, 5)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash in the middle of writing a record to the last segment.
+	segments, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(segments) < 2 {
+		t.Fatalf("got %d segments; want at least 2", len(segments))
+	}
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 20, 1, 2})
+	f.Close()
+
+	server.SetDown(false)
+	w = newSpoolWriter(t, server.URL, influxdb.SpoolOptions{
+		Dir:         dir,
+		SegmentSize: 64,
+	})
+	defer w.Close()
+
+	writeSpoolPoints(t, w, 5, 1)
+	drain(t, w)
+
+	want := []string{"cpu value=0i", "cpu value=1i", "cpu value=2i", "cpu value=3i", "cpu value=4i", "cpu value=5i"}
+	if got := server.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines:\n\ngot=%q\n\nwant=%q", got, want)
+	}
+	if got, want := w.Stats().Dropped, int64(1); got != want {
+		t.Errorf("Dropped = %d; want %d", got, want)
+	}
+}
+
+func TestSpoolWriter_RecoverPosition(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	dir := t.TempDir()
+	w := newSpoolWriter(t, server.URL, influxdb.SpoolOptions{Dir: dir})
+	writeSpoolPoints(t, w, 0, 2)
+	drain(t, w)
+
+	// Points written while the server is down should be the only points
+	// written after the spool is opened again.
+	server.SetDown(true)
+	writeSpoolPoints(t, w, 2, 2)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	server.SetDown(false)
+	w = newSpoolWriter(t, server.URL, influxdb.SpoolOptions{Dir: dir})
+	defer w.Close()
+
+	if got, want := w.Stats().Records, int64(2); got != want {
+		t.Errorf("Records = %d; want %d", got, want)
+	}
+	drain(t, w)
+
+	want := []string{"cpu value=0i", "cpu value=1i", "cpu value=2i", "cpu value=3i"}
+	if got := server.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines:\n\ngot=%q\n\nwant=%q", got, want)
+	}
+}
+
+func TestSpoolWriter_RetryPolicy(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "expected error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The default policy would discard a write that failed with 500.
+	w := newSpoolWriter(t, server.URL, influxdb.SpoolOptions{
+		Dir:        t.TempDir(),
+		MaxBackoff: 10 * time.Second,
+		RetryPolicy: &influxdb.RetryPolicy{
+			RetryableStatusCodes: []int{http.StatusInternalServerError},
+		},
+	})
+	defer w.Close()
+
+	writeSpoolPoints(t, w, 0, 1)
+	drain(t, w)
+
+	if got, want := w.Stats().Failed, int64(0); got != want {
+		t.Errorf("Failed = %d; want %d", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := len(attempts), 2; got != want {
+		t.Fatalf("attempts = %d; want %d", got, want)
+	} else if wait := attempts[1].Sub(attempts[0]); wait < time.Second {
+		t.Errorf("retried after %s; want at least %s", wait, time.Second)
+	}
+}
+
+func TestSpoolWriter_Reject(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	server.SetDown(true)
+
+	w := newSpoolWriter(t, server.URL, influxdb.SpoolOptions{
+		Dir:     t.TempDir(),
+		MaxSize: 50,
+	})
+	defer w.Close()
+
+	// Each record is 13 bytes of line protocol and an 8 byte header.
+	writeSpoolPoints(t, w, 0, 2)
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": 2},
+	}
+	if got, want := w.WritePoint(pt), influxdb.ErrSpoolFull; got != want {
+		t.Fatalf("err = %v; want %v", got, want)
+	}
+
+	stats := w.Stats()
+	if got, want := stats.Records, int64(2); got != want {
+		t.Errorf("Records = %d; want %d", got, want)
+	}
+	if got, want := stats.Rejected, int64(1); got != want {
+		t.Errorf("Rejected = %d; want %d", got, want)
+	}
+}
+
+func TestSpoolWriter_DropOldest(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	server.SetDown(true)
+
+	w := newSpoolWriter(t, server.URL, influxdb.SpoolOptions{
+		Dir:         t.TempDir(),
+		SegmentSize: 21,
+		MaxSize:     50,
+		Policy:      influxdb.SpoolDropOldest,
+	})
+	defer w.Close()
+
+	writeSpoolPoints(t, w, 0, 5)
+	stats := w.Stats()
+	if got, want := stats.Segments, 2; got != want {
+		t.Errorf("Segments = %d; want %d", got, want)
+	}
+	if got, want := stats.Records, int64(2); got != want {
+		t.Errorf("Records = %d; want %d", got, want)
+	}
+	if got, want := stats.Dropped, int64(3); got != want {
+		t.Errorf("Dropped = %d; want %d", got, want)
+	}
+
+	server.SetDown(false)
+	drain(t, w)
+
+	want := []string{"cpu value=3i", "cpu value=4i"}
+	if got := server.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected lines:\n\ngot=%q\n\nwant=%q", got, want)
+	}
+}
+
+func TestSpoolWriter_Closed(t *testing.T) {
+	w := newSpoolWriter(t, "http://localhost:8086", influxdb.SpoolOptions{Dir: t.TempDir()})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w.Close(), influxdb.ErrWriterClosed; got != want {
+		t.Errorf("Close() = %v; want %v", got, want)
+	}
+	if _, got := w.Write([]byte("cpu value=1")); got != influxdb.ErrWriterClosed {
+		t.Errorf("Write() = %v; want %v", got, influxdb.ErrWriterClosed)
+	}
+}
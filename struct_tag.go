@@ -0,0 +1,125 @@
+package influxdb
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldKind is the kind of value a struct field is mapped to by the influx
+// struct tag.
+type fieldKind int
+
+const (
+	fieldKindMeasurement fieldKind = iota + 1
+	fieldKindTag
+	fieldKindField
+	fieldKindTime
+)
+
+// structField describes a struct field that has an influx struct tag.
+type structField struct {
+	index     []int
+	goName    string
+	kind      fieldKind
+	name      string
+	omitEmpty bool
+}
+
+// structInfo holds the mapped fields for a struct type.
+type structInfo struct {
+	fields []structField
+}
+
+// structInfoCache caches the structInfo for each type so the struct tags are
+// only parsed once.
+var structInfoCache sync.Map
+
+// getStructInfo returns the structInfo for the struct type.
+func getStructInfo(t reflect.Type) *structInfo {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+
+	info := &structInfo{}
+	collectStructFields(t, nil, map[reflect.Type]bool{t: true}, &info.fields)
+	actual, _ := structInfoCache.LoadOrStore(t, info)
+	return actual.(*structInfo)
+}
+
+// collectStructFields appends the tagged fields of the struct type to fields.
+// Embedded structs without a tag are traversed and their fields are promoted.
+// The visited types are the structs currently being traversed so a struct
+// that embeds a pointer to itself is not traversed again.
+func collectStructFields(t reflect.Type, index []int, visited map[reflect.Type]bool, fields *[]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("influx")
+		if tag == "-" {
+			continue
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		if !hasTag {
+			if f.Anonymous {
+				ft := f.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct && !visited[ft] {
+					visited[ft] = true
+					collectStructFields(ft, fieldIndex, visited, fields)
+					delete(visited, ft)
+				}
+			}
+			continue
+		} else if f.PkgPath != "" {
+			// Unexported fields cannot be set or read. This includes
+			// embedded fields with an unexported type.
+			continue
+		}
+
+		sf, ok := parseStructTag(tag)
+		if !ok {
+			continue
+		}
+		sf.index = fieldIndex
+		sf.goName = f.Name
+		if sf.name == "" {
+			sf.name = f.Name
+		}
+		*fields = append(*fields, sf)
+	}
+}
+
+// parseStructTag parses the contents of an influx struct tag. The tag has the
+// format kind[,name][,omitempty].
+func parseStructTag(tag string) (structField, bool) {
+	parts := strings.Split(tag, ",")
+
+	var sf structField
+	switch parts[0] {
+	case "measurement":
+		sf.kind = fieldKindMeasurement
+	case "tag":
+		sf.kind = fieldKindTag
+	case "field", "column":
+		sf.kind = fieldKindField
+	case "time":
+		sf.kind = fieldKindTime
+	default:
+		return sf, false
+	}
+
+	for i, opt := range parts[1:] {
+		if opt == "omitempty" {
+			sf.omitEmpty = true
+		} else if i == 0 {
+			sf.name = opt
+		}
+	}
+	return sf, true
+}
@@ -0,0 +1,184 @@
+package influxdb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PointLimits are the limits used when validating a point. A limit of zero
+// disables that check.
+type PointLimits struct {
+	// MaxNameLength is the maximum length of a measurement name in bytes.
+	MaxNameLength int
+
+	// MaxKeyLength is the maximum length of a tag or field key in bytes.
+	MaxKeyLength int
+
+	// MaxTagValueLength is the maximum length of a tag value in bytes.
+	MaxTagValueLength int
+
+	// MaxStringLength is the maximum length of a string field value in bytes.
+	MaxStringLength int
+}
+
+// DefaultPointLimits are the limits enforced by the server.
+var DefaultPointLimits = PointLimits{
+	MaxNameLength:     math.MaxUint16,
+	MaxKeyLength:      math.MaxUint16,
+	MaxTagValueLength: math.MaxUint16,
+	MaxStringLength:   64 * 1024,
+}
+
+// reservedKeys are the tag and field keys that the server does not allow.
+var reservedKeys = map[string]bool{
+	"time":         true,
+	"_field":       true,
+	"_measurement": true,
+}
+
+var (
+	// MinTime is the earliest time that can be written to the server.
+	MinTime = time.Unix(0, math.MinInt64+2).UTC()
+
+	// MaxTime is the latest time that can be written to the server.
+	MaxTime = time.Unix(0, math.MaxInt64-1).UTC()
+)
+
+// Validate checks the point for mistakes that would cause it to be rejected
+// by the server using the DefaultPointLimits. If any are found, an
+// ErrValidation is returned with an ErrInvalidPoint for each problem.
+func (pt *Point) Validate() error {
+	return pt.ValidateLimits(DefaultPointLimits)
+}
+
+// ValidateLimits checks the point like Validate using the given limits.
+func (pt *Point) ValidateLimits(limits PointLimits) error {
+	var errs []error
+	invalid := func(key, format string, args ...interface{}) {
+		errs = append(errs, ErrInvalidPoint{
+			Name:   pt.Name,
+			Key:    key,
+			Reason: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if reason := checkIdentifier(pt.Name); reason != "" {
+		invalid("", "measurement %s", reason)
+	} else if pt.Name[0] == '#' {
+		invalid("", "measurement begins with #")
+	} else if exceeds(pt.Name, limits.MaxNameLength) {
+		invalid("", "measurement is longer than %d bytes", limits.MaxNameLength)
+	}
+
+	seen := make(map[string]bool, len(pt.Tags))
+	for _, t := range pt.Tags {
+		if t.Value == "" {
+			// Tags with an empty value are left out when the point is
+			// encoded.
+			continue
+		} else if reason := checkIdentifier(t.Key); reason != "" {
+			invalid(t.Key, "tag key %s", reason)
+		} else if reservedKeys[t.Key] {
+			invalid(t.Key, "tag key is reserved")
+		} else if seen[t.Key] {
+			invalid(t.Key, "duplicate tag key")
+		} else if exceeds(t.Key, limits.MaxKeyLength) {
+			invalid(t.Key, "tag key is longer than %d bytes", limits.MaxKeyLength)
+		}
+		seen[t.Key] = true
+
+		if reason := checkIdentifier(t.Value); reason != "" {
+			invalid(t.Key, "tag value %s", reason)
+		} else if exceeds(t.Value, limits.MaxTagValueLength) {
+			invalid(t.Key, "tag value is longer than %d bytes", limits.MaxTagValueLength)
+		}
+	}
+
+	// Check the fields in a consistent order so the errors are always
+	// reported in the same order.
+	keys := make([]string, 0, len(pt.Fields))
+	for k := range pt.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		buf [64]byte
+		n   int
+	)
+	for _, k := range keys {
+		v := pt.Fields[k]
+		if reason := checkIdentifier(k); reason != "" {
+			invalid(k, "field key %s", reason)
+		} else if reservedKeys[k] {
+			invalid(k, "field key is reserved")
+		} else if exceeds(k, limits.MaxKeyLength) {
+			invalid(k, "field key is longer than %d bytes", limits.MaxKeyLength)
+		}
+
+		// Encode the value to check that it has a supported type. Unsigned
+		// integers are allowed since their range depends on the write options.
+		if _, err := appendValue(buf[:0], v, EncodeOptions{Unsigned: true}); err != nil {
+			if err == errNilValue {
+				continue
+			} else if _, ok := err.(ErrInvalidFloat); ok {
+				invalid(k, "field value %v is not a valid float", v)
+			} else {
+				invalid(k, "field value: %s", err)
+			}
+		} else if s, ok := v.(string); ok && exceeds(s, limits.MaxStringLength) {
+			invalid(k, "field value is longer than %d bytes", limits.MaxStringLength)
+		}
+		n++
+	}
+	if n == 0 {
+		invalid("", "no fields")
+	}
+
+	if !pt.Time.IsZero() && (pt.Time.Before(MinTime) || pt.Time.After(MaxTime)) {
+		invalid("", "time %s is outside the range %s to %s",
+			pt.Time.Format(time.RFC3339Nano), MinTime.Format(time.RFC3339Nano), MaxTime.Format(time.RFC3339Nano))
+	}
+
+	if len(errs) > 0 {
+		return ErrValidation{Errors: errs}
+	}
+	return nil
+}
+
+// exceeds returns true if the string is longer than the limit. A limit of
+// zero is unlimited.
+func exceeds(s string, limit int) bool {
+	return limit > 0 && len(s) > limit
+}
+
+// validatePoints validates each of the points with the limits. The errors for
+// every point are combined into a single ErrValidation with the index of the
+// point set on each error.
+func validatePoints(pts []Point, limits *PointLimits) error {
+	if limits == nil {
+		limits = &DefaultPointLimits
+	}
+
+	var errs []error
+	for i := range pts {
+		err := pts[i].ValidateLimits(*limits)
+		if err == nil {
+			continue
+		}
+		for _, err := range err.(ErrValidation).Errors {
+			if e, ok := err.(ErrInvalidPoint); ok {
+				e.Index = i
+				err = e
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return ErrValidation{Errors: errs}
+	}
+	return nil
+}
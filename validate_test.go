@@ -0,0 +1,154 @@
+package influxdb_test
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb-client"
+)
+
+func TestPoint_Validate(t *testing.T) {
+	pt := influxdb.Point{
+		Name: "cpu",
+		Tags: influxdb.Tags{
+			{Key: "host", Value: "server01"},
+			{Key: "region", Value: "us-west"},
+			{Key: "az", Value: ""},
+		},
+		Fields: map[string]interface{}{
+			"value": float64(5),
+			"count": uint64(math.MaxUint64),
+			"state": "running",
+		},
+		Time: time.Unix(0, 1000),
+	}
+	if err := pt.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestPoint_Validate_Errors(t *testing.T) {
+	pt := influxdb.Point{
+		Name: "cpu",
+		Tags: influxdb.Tags{
+			{Key: "host", Value: "server01"},
+			{Key: "time", Value: "now"},
+			{Key: "host", Value: "server02"},
+		},
+		Fields: map[string]interface{}{
+			"_field": int64(1),
+			"nan":    math.NaN(),
+			"slice":  []int{1},
+		},
+		Time: time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	err := pt.Validate()
+	verr, ok := err.(influxdb.ErrValidation)
+	if !ok {
+		t.Fatalf("got error %#v; want %T", err, influxdb.ErrValidation{})
+	}
+
+	var reasons []string
+	for _, err := range verr.Errors {
+		e, ok := err.(influxdb.ErrInvalidPoint)
+		if !ok {
+			t.Fatalf("got error %#v; want %T", err, influxdb.ErrInvalidPoint{})
+		}
+		reasons = append(reasons, e.Key+": "+e.Reason)
+	}
+
+	want := []string{
+		"time: tag key is reserved",
+		"host: duplicate tag key",
+		"_field: field key is reserved",
+		"nan: field value NaN is not a valid float",
+		"slice: field value: invalid field type: []int",
+		": time 2300-01-01T00:00:00Z is outside the range 1677-09-21T00:12:43.145224194Z to 2262-04-11T23:47:16.854775806Z",
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Fatalf("unexpected errors:\n\ngot=%q\nwant=%q\n", reasons, want)
+	}
+
+	var invalid influxdb.ErrInvalidPoint
+	if !errors.As(err, &invalid) || invalid.Key != "time" {
+		t.Fatalf("expected errors.As to find the first invalid point, got %#v", invalid)
+	}
+}
+
+func TestPoint_ValidateLimits(t *testing.T) {
+	pt := influxdb.Point{
+		Name:   "measurement",
+		Tags:   influxdb.Tags{{Key: "host", Value: "server01"}},
+		Fields: map[string]interface{}{"value": "a long string"},
+	}
+
+	limits := influxdb.PointLimits{
+		MaxNameLength:     5,
+		MaxTagValueLength: 4,
+		MaxStringLength:   8,
+	}
+	err := pt.ValidateLimits(limits)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	want := "3 validation errors: " +
+		`invalid point "measurement": measurement is longer than 5 bytes; ` +
+		`invalid point "measurement": key "host": tag value is longer than 4 bytes; ` +
+		`invalid point "measurement": key "value": field value is longer than 8 bytes`
+	if got := err.Error(); got != want {
+		t.Fatalf("unexpected error:\n\ngot=%s\nwant=%s\n", got, want)
+	}
+
+	if err := pt.ValidateLimits(influxdb.PointLimits{}); err != nil {
+		t.Fatalf("unexpected error with no limits: %s", err)
+	}
+}
+
+func TestWriter_Validate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("invalid points should not be written")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.Database = "db0"
+	writer.Validate = true
+	writer.Limits = &influxdb.PointLimits{MaxNameLength: 3}
+
+	pts := []influxdb.Point{
+		{Name: "cpu", Fields: map[string]interface{}{"value": 1}},
+		{Name: "memory", Fields: map[string]interface{}{"value": 1}},
+	}
+	_, err = writer.WriteBatch(pts)
+
+	verr, ok := err.(influxdb.ErrValidation)
+	if !ok {
+		t.Fatalf("got error %#v; want %T", err, influxdb.ErrValidation{})
+	}
+	want := []error{influxdb.ErrInvalidPoint{
+		Name:   "memory",
+		Reason: "measurement is longer than 3 bytes",
+		Index:  1,
+	}}
+	if !reflect.DeepEqual(verr.Errors, want) {
+		t.Fatalf("got %#v; want %#v", verr.Errors, want)
+	}
+
+	if _, err := writer.WritePoint(influxdb.Point{Name: "cpu"}); err == nil || !strings.Contains(err.Error(), "no fields") {
+		t.Fatalf("got error %v; want no fields", err)
+	}
+}
@@ -1,14 +1,34 @@
 package influxdb
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 )
 
+// WriteAPI is the HTTP API used to write points.
+type WriteAPI string
+
+const (
+	// WriteAPIV1 writes to the /write endpoint of an InfluxDB 1.x server
+	// using the Database and RetentionPolicy.
+	WriteAPIV1 = WriteAPI("")
+
+	// WriteAPIV2 writes to the /api/v2/write endpoint of an InfluxDB 2.x
+	// server using the Org and Bucket.
+	WriteAPIV2 = WriteAPI("v2")
+)
+
+func (a WriteAPI) String() string {
+	return string(a)
+}
+
 // WriteOptions is a set of configuration options for configuring writers.
 type WriteOptions struct {
 	Database        string
@@ -16,13 +36,68 @@ type WriteOptions struct {
 	Consistency     Consistency
 	Precision       Precision
 	Protocol        Protocol
+
+	// API is the HTTP API used to write points. The Database,
+	// RetentionPolicy and Consistency are only used by WriteAPIV1 and the
+	// Org and Bucket are only used by WriteAPIV2.
+	API WriteAPI
+
+	// Org is the name or ID of the organization that owns the bucket.
+	Org string
+
+	// Bucket is the name of the bucket to write to. It is required when
+	// using WriteAPIV2.
+	Bucket string
+
+	// Unsigned writes unsigned integers with the unsigned integer type
+	// instead of converting them to signed integers. The server must
+	// support unsigned integers to use this option.
+	Unsigned bool
+
+	// Sorted writes the tags and fields of each point sorted by key so the
+	// same point is always encoded the same way.
+	Sorted bool
+
+	// Validate checks each point with Point.Validate before it is encoded.
+	// If any point is invalid, an ErrValidation is returned and nothing is
+	// written.
+	Validate bool
+
+	// Limits are the limits used to validate points. If nil, the
+	// DefaultPointLimits are used.
+	Limits *PointLimits
+
+	// Compression compresses the body of each write request.
+	Compression Compression
+
+	// CompressionLevel is the compression level to use. If zero, the
+	// default level for the compression is used. Use NoCompressionLevel
+	// for gzip.NoCompression since its value is zero.
+	CompressionLevel int
+
+	// ChunkSize is the maximum number of bytes sent in a single request by
+	// ReadFrom. If zero, DefaultWriteChunkSize is used.
+	ChunkSize int
 }
 
+// DefaultWriteChunkSize is the default maximum size of a request sent by
+// Writer.ReadFrom.
+const DefaultWriteChunkSize = 1 << 20
+
 // Clone creates a copy of the WriteOptions.
 func (opt *WriteOptions) Clone() WriteOptions {
 	return *opt
 }
 
+// encodeOptions returns the options used to encode points for these write options.
+func (opt *WriteOptions) encodeOptions() EncodeOptions {
+	return EncodeOptions{
+		Precision: opt.Precision,
+		Unsigned:  opt.Unsigned,
+		Sorted:    opt.Sorted,
+	}
+}
+
 // Writer holds onto write options and acts as a convenience method for performing writes.
 type Writer struct {
 	c *Client
@@ -34,56 +109,70 @@ type Writer struct {
 // server understands the format. Each call to Write will make a single HTTP
 // write request.
 func (w *Writer) Write(data []byte) (n int, err error) {
+	return w.WriteContext(context.Background(), data)
+}
+
+// WriteContext writes the bytes to the server like Write. The context will
+// cancel the request.
+func (w *Writer) WriteContext(ctx context.Context, data []byte) (n int, err error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
 
-	values := url.Values{}
-	if w.Database != "" {
-		values.Set("db", w.Database)
-	}
-	if w.RetentionPolicy != "" {
-		values.Set("rp", w.RetentionPolicy)
-	}
-	if consistency := w.Consistency.String(); consistency != "" {
-		values.Set("consistency", consistency)
-	}
-	if precision := w.Precision.String(); precision != "" {
-		values.Set("precision", precision)
+	u, err := w.url()
+	if err != nil {
+		return 0, err
 	}
 
-	u := w.c.url("/write")
-	u.RawQuery = values.Encode()
+	body := data
+	switch w.Compression {
+	case CompressionNone:
+	case CompressionGzip:
+		var buf bytes.Buffer
+		if err := gzipCompress(&buf, data, w.CompressionLevel); err != nil {
+			return 0, err
+		}
+		body = buf.Bytes()
+	default:
+		return 0, fmt.Errorf("unknown compression: %s", w.Compression)
+	}
 
-	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(data))
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
 	if err != nil {
 		return 0, err
 	}
+	req = req.WithContext(ctx)
+
+	if w.Compression != CompressionNone {
+		req.Header.Set("Content-Encoding", w.Compression.String())
+	}
 
 	p := w.Protocol
 	if p == nil {
 		p = DefaultWriteProtocol
 	}
 	req.Header.Set("Content-Type", p.ContentType())
-	if w.c.Auth != nil {
-		req.SetBasicAuth(w.c.Auth.Username, w.c.Auth.Password)
-	}
+	w.c.Auth.setAuth(req)
 
-	resp, err := w.c.Do(req)
+	resp, err := w.c.do(req, true)
 	if err != nil {
 		return 0, err
 	}
 
 	switch resp.StatusCode / 100 {
 	case 2:
+		resp.Body.Close()
 		return len(data), nil
 	case 4:
 		// This is a client error. Read the error message to learn what type of
 		// error this is.
 		err := ReadError(resp)
 		if strings.HasPrefix(err.Error(), "partial write:") {
-			// So we DID write, but it was a partial write. Wrap the error message.
-			return len(data), ErrPartialWrite{Err: err.Error()}
+			// So we DID write, but it was a partial write. Find out which
+			// lines were dropped and report the number of bytes that were
+			// written.
+			perr, n := parsePartialWrite(err.Error(), data)
+			return n, perr
 		}
 		return 0, err
 	default:
@@ -94,48 +183,277 @@ func (w *Writer) Write(data []byte) (n int, err error) {
 	}
 }
 
-// ReadFrom will read data from another io.Reader. This is used so io.Copy can
-// be supported. In the future, this function may become protocol aware. For
-// now, it reads the entire output into a buffer and writes from that buffer.
-func (w *Writer) ReadFrom(r io.Reader) (n int, err error) {
-	data, err := ioutil.ReadAll(r)
-	if err != nil {
-		return 0, err
+// url returns the URL for a write request using the API of the writer.
+func (w *Writer) url() (url.URL, error) {
+	values := url.Values{}
+	var u url.URL
+	switch w.API {
+	case WriteAPIV1:
+		if w.Database != "" {
+			values.Set("db", w.Database)
+		}
+		if w.RetentionPolicy != "" {
+			values.Set("rp", w.RetentionPolicy)
+		}
+		if consistency := w.Consistency.String(); consistency != "" {
+			values.Set("consistency", consistency)
+		}
+		if precision := w.Precision.String(); precision != "" {
+			values.Set("precision", precision)
+		}
+		u = w.c.url("/write")
+	case WriteAPIV2:
+		if w.Org != "" {
+			values.Set("org", w.Org)
+		}
+		if w.Bucket == "" {
+			return url.URL{}, fmt.Errorf("bucket is required to write with the v2 api")
+		}
+		values.Set("bucket", w.Bucket)
+		if w.Precision != "" {
+			precision, err := w.Precision.v2()
+			if err != nil {
+				return url.URL{}, err
+			}
+			values.Set("precision", precision)
+		}
+		u = w.c.url("/api/v2/write")
+	default:
+		return url.URL{}, fmt.Errorf("unknown write api: %s", w.API)
 	}
-	return w.Write(data)
+	u.RawQuery = values.Encode()
+	return u, nil
+}
+
+// ReadFrom reads line protocol from the io.Reader and writes it to the server.
+// This is used so io.Copy can be supported. The input is split between points
+// into chunks of at most ChunkSize bytes and each chunk is written with a
+// separate request, so the input is never held in memory all at once. A point
+// that is larger than ChunkSize is written in a chunk by itself. Comments and
+// blank lines are written in the same chunk as the point that follows them.
+// Comments and blank lines at the end of the input are added to the last
+// chunk if they fit and are otherwise skipped, since there is nothing for the
+// server to write.
+//
+// The returned count is the number of bytes of the input that were written or
+// skipped. If a chunk fails to write, an ErrWriteChunk is returned with the
+// location of the chunk in the input and no more chunks are written.
+//
+// The count is an int64 so that Writer implements io.ReaderFrom. Earlier
+// versions returned an int, so callers that assign the count to an int must
+// now convert it.
+func (w *Writer) ReadFrom(r io.Reader) (n int64, err error) {
+	size := w.ChunkSize
+	if size <= 0 {
+		size = DefaultWriteChunkSize
+	}
+
+	var (
+		chunk  []byte
+		point  []byte
+		offset int64
+		line   = 1
+
+		// start is the position in point after any comments and blank
+		// lines that are waiting for the next point.
+		start int
+	)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		written, err := w.Write(chunk)
+		n += int64(written)
+		if err != nil {
+			return ErrWriteChunk{Offset: offset, Line: line, Err: err}
+		}
+		offset += int64(len(chunk))
+		line += bytes.Count(chunk, []byte{'\n'})
+		chunk = nil
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		b, rerr := br.ReadSlice('\n')
+		point = append(point, b...)
+		if rerr == bufio.ErrBufferFull {
+			continue
+		} else if rerr != nil && rerr != io.EOF {
+			return n, rerr
+		}
+
+		if isComment(point[start:]) {
+			if rerr != io.EOF {
+				// Hold onto the comment until the next point so it is not
+				// written in a chunk by itself.
+				start = len(point)
+				continue
+			}
+
+			// There is no point left to attach the comments to.
+			if len(chunk) == 0 || len(chunk)+len(point) > size {
+				if err := flush(); err != nil {
+					return n, err
+				}
+				return n + int64(len(point)), nil
+			}
+			chunk = append(chunk, point...)
+			return n, flush()
+		}
+
+		// Add the point to the chunk once it is complete. A string field
+		// value may contain a newline so a point can span multiple lines.
+		if rerr == io.EOF || pointComplete(point[start:]) {
+			if len(chunk) > 0 && len(chunk)+len(point) > size {
+				if err := flush(); err != nil {
+					return n, err
+				}
+			}
+			if chunk == nil {
+				chunk = make([]byte, 0, size)
+			}
+			chunk = append(chunk, point...)
+			point = point[:0]
+			start = 0
+		}
+
+		if rerr == io.EOF {
+			return n, flush()
+		}
+	}
+}
+
+// isComment returns true if the line protocol is a blank line or a comment.
+func isComment(b []byte) bool {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	return len(b) == 0 || b[0] == '#'
+}
+
+// pointComplete returns false if the line protocol ends in the middle of a
+// string field value.
+func pointComplete(b []byte) bool {
+	b = bytes.TrimLeft(b, " \t")
+
+	// Skip the measurement and tags and look for string field values.
+	for i := scanTo(b, 0, " "); i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '=':
+			if i+1 < len(b) && b[i+1] == '"' {
+				end := scanString(b, i+2)
+				if end < 0 {
+					return false
+				}
+				i = end
+			}
+		}
+	}
+	return true
 }
 
 // WritePoint will encode a single point in the protocol format and write it to
 // the server. While useful for writing a single point, this method is very
-// inefficient when writing many points.
+// inefficient when writing many points. Use a BatchWriter to buffer points and
+// write them in batches.
 func (w *Writer) WritePoint(pt Point) (n int, err error) {
-	p := w.Protocol
-	if p == nil {
-		p = DefaultWriteProtocol
-	}
-	opts := EncodeOptions{Precision: w.Precision}
+	return w.WritePointContext(context.Background(), pt)
+}
 
-	var buf bytes.Buffer
-	if err := p.Encode(&buf, &pt, opts); err != nil {
+// WritePointContext will encode a single point in the protocol format and
+// write it to the server. The context will cancel the request.
+func (w *Writer) WritePointContext(ctx context.Context, pt Point) (n int, err error) {
+	data, err := w.encodeBatch([]Point{pt}, nil)
+	if err != nil {
 		return 0, err
 	}
-	return w.Write(buf.Bytes())
+	return w.WriteContext(ctx, data)
 }
 
 // WriteBatch will encode a batch of points in the protocol format and write it
 // to the server. It makes no attempt to split the number of points in the batch.
 func (w *Writer) WriteBatch(pts []Point) (n int, err error) {
+	return w.WriteBatchContext(context.Background(), pts)
+}
+
+// WriteBatchContext will encode a batch of points in the protocol format and
+// write it to the server. The context will cancel the request.
+func (w *Writer) WriteBatchContext(ctx context.Context, pts []Point) (n int, err error) {
+	data, err := w.encodeBatch(pts, nil)
+	if err != nil {
+		return 0, err
+	}
+	return w.WriteContext(ctx, data)
+}
+
+// encodeBatch validates the points if Validate is set and encodes them with
+// the protocol and options of the Writer. If offsets is not nil, it must be
+// the same length as pts and the end of each encoded point is stored in it.
+func (w *Writer) encodeBatch(pts []Point, offsets []int) ([]byte, error) {
 	p := w.Protocol
 	if p == nil {
 		p = DefaultWriteProtocol
 	}
-	opts := EncodeOptions{Precision: w.Precision}
+	opts := w.encodeOptions()
+
+	if w.Validate {
+		if err := validatePoints(pts, w.Limits); err != nil {
+			return nil, err
+		}
+	}
 
 	var buf bytes.Buffer
-	for _, pt := range pts {
-		if err := p.Encode(&buf, &pt, opts); err != nil {
+	for i := range pts {
+		if err := p.Encode(&buf, &pts[i], opts); err != nil {
+			return nil, err
+		}
+		if offsets != nil {
+			offsets[i] = buf.Len()
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteStruct converts the struct into a point with MarshalPoint and writes
+// it to the server.
+func (w *Writer) WriteStruct(v interface{}) (n int, err error) {
+	return w.WriteStructContext(context.Background(), v)
+}
+
+// WriteStructContext converts the struct into a point with MarshalPoint and
+// writes it to the server. The context will cancel the request.
+func (w *Writer) WriteStructContext(ctx context.Context, v interface{}) (n int, err error) {
+	pt, err := MarshalPoint(v)
+	if err != nil {
+		return 0, err
+	}
+	return w.WritePointContext(ctx, pt)
+}
+
+// WriteStructs converts every struct in the slice into a point with
+// MarshalPoint and writes them to the server in a single batch.
+func (w *Writer) WriteStructs(v interface{}) (n int, err error) {
+	return w.WriteStructsContext(context.Background(), v)
+}
+
+// WriteStructsContext converts every struct in the slice into a point with
+// MarshalPoint and writes them to the server in a single batch. The context
+// will cancel the request.
+func (w *Writer) WriteStructsContext(ctx context.Context, v interface{}) (n int, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return 0, fmt.Errorf("cannot write %T as a batch of structs", v)
+	}
+
+	pts := make([]Point, rv.Len())
+	for i := range pts {
+		pt, err := MarshalPoint(rv.Index(i).Interface())
+		if err != nil {
 			return 0, err
 		}
+		pts[i] = pt
 	}
-	return w.Write(buf.Bytes())
+	return w.WriteBatchContext(ctx, pts)
 }
@@ -1,10 +1,13 @@
 package influxdb_test
 
 import (
+	"context"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -101,6 +104,88 @@ func TestWriter_Copy(t *testing.T) {
 	}
 }
 
+func TestWriter_ReadFrom_Chunks(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, string(data))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.ChunkSize = 32
+
+	input := "cpu value=1\n" +
+		"cpu value=2\n" +
+		"log msg=\"line one\nline two\"\n" +
+		"# comment\n" +
+		"cpu,host=a-very-long-hostname value=3\n" +
+		"cpu value=4\n" +
+		"# trailing comment\n"
+	n, err := writer.ReadFrom(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if n != int64(len(input)) {
+		t.Fatalf("got %d bytes written; want %d", n, len(input))
+	}
+
+	want := []string{
+		"cpu value=1\ncpu value=2\n",
+		"log msg=\"line one\nline two\"\n",
+		"# comment\ncpu,host=a-very-long-hostname value=3\n",
+		"cpu value=4\n# trailing comment\n",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Fatalf("unexpected requests:\n\ngot=%q\nwant=%q\n", requests, want)
+	}
+}
+
+func TestWriter_ReadFrom_Error(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "unable to parse")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.ChunkSize = 24
+
+	input := "cpu value=1\ncpu value=2\ncpu value=3\ncpu value=4\ncpu value=5\n"
+	n, err := writer.ReadFrom(strings.NewReader(input))
+	if n != 24 {
+		t.Errorf("got %d bytes written; want 24", n)
+	}
+
+	chunkErr, ok := err.(influxdb.ErrWriteChunk)
+	if !ok {
+		t.Fatalf("got error %#v; want %T", err, influxdb.ErrWriteChunk{})
+	} else if chunkErr.Offset != 24 || chunkErr.Line != 3 {
+		t.Fatalf("got offset %d and line %d; want offset 24 and line 3", chunkErr.Offset, chunkErr.Line)
+	} else if !errors.Is(err, influxdb.ErrBadRequest) {
+		t.Fatalf("expected the chunk error to wrap the request error, got %v", chunkErr.Err)
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests; want 2", requests)
+	}
+}
+
 func TestWriter_ConsistencyAndPrecision(t *testing.T) {
 	protocol := influxdb.DefaultWriteProtocol
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -155,3 +240,129 @@ func TestWriter_ConsistencyAndPrecision(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestWriter_WriteContext_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not have been sent")
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer := client.Writer()
+	writer.Database = "db0"
+	if n, err := writer.WriteContext(ctx, []byte("cpu value=5\n")); err == nil {
+		t.Fatal("expected error, got nil")
+	} else if n != 0 {
+		t.Fatalf("n = %d; want 0", n)
+	}
+}
+
+func TestWriter_V2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/api/v2/write"; got != want {
+			t.Errorf("Path = %q; want %q", got, want)
+		}
+
+		values := r.URL.Query()
+		if got, want := values.Get("org"), "my-org"; got != want {
+			t.Errorf("org = %q; want %q", got, want)
+		}
+		if got, want := values.Get("bucket"), "telegraf"; got != want {
+			t.Errorf("bucket = %q; want %q", got, want)
+		}
+		if got, want := values.Get("precision"), "us"; got != want {
+			t.Errorf("precision = %q; want %q", got, want)
+		}
+		if got, want := values.Get("db"), ""; got != want {
+			t.Errorf("db = %q; want %q", got, want)
+		}
+		if got, want := r.Header.Get("Authorization"), "Token my-token"; got != want {
+			t.Errorf("Authorization = %q; want %q", got, want)
+		}
+
+		data, _ := ioutil.ReadAll(r.Body)
+		if got, want := string(data), "cpu value=5 10000000\n"; got != want {
+			t.Errorf("body = %q; want %q", got, want)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Auth = &influxdb.Auth{Token: "my-token"}
+
+	writer := client.Writer()
+	writer.API = influxdb.WriteAPIV2
+	writer.Org = "my-org"
+	writer.Bucket = "telegraf"
+	writer.Database = "ignored"
+	writer.Precision = influxdb.PrecisionMicrosecond
+
+	pt := influxdb.Point{
+		Name:   "cpu",
+		Fields: map[string]interface{}{"value": 5.0},
+		Time:   time.Unix(10, 0),
+	}
+	if _, err := writer.WritePoint(pt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriter_V2_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"code":"unauthorized","message":"unauthorized access"}`)
+	}))
+	defer server.Close()
+
+	client, err := influxdb.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.API = influxdb.WriteAPIV2
+	writer.Bucket = "telegraf"
+
+	_, err = writer.Write([]byte("cpu value=5\n"))
+	var httpErr influxdb.ErrHTTP
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v; want %T", err, httpErr)
+	}
+	if got, want := httpErr.Code, "unauthorized"; got != want {
+		t.Errorf("Code = %q; want %q", got, want)
+	}
+	if got, want := httpErr.Message, "unauthorized access"; got != want {
+		t.Errorf("Message = %q; want %q", got, want)
+	}
+	if !errors.Is(err, influxdb.ErrUnauthorized) {
+		t.Errorf("errors.Is(%v, ErrUnauthorized) = false; want true", err)
+	}
+}
+
+func TestWriter_V2_Precision(t *testing.T) {
+	client, err := influxdb.NewClient("http://localhost:8086")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := client.Writer()
+	writer.API = influxdb.WriteAPIV2
+	writer.Bucket = "telegraf"
+	writer.Precision = influxdb.PrecisionHour
+	if _, err := writer.Write([]byte("cpu value=5\n")); err == nil {
+		t.Fatal("expected error")
+	}
+}